@@ -8,12 +8,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 	"unsafe"
 
 	"cuelang.org/go/cue"
-	"cuelang.org/go/cue/build"
 	"cuelang.org/go/cue/cuecontext"
+	cueerrors "cuelang.org/go/cue/errors"
 	"cuelang.org/go/cue/load"
 )
 
@@ -22,6 +25,152 @@ func cue_free_string(s *C.char) {
 	C.free(unsafe.Pointer(s))
 }
 
+// evalOptions configures cue_eval_package_ex. ValidateOnly skips building the
+// JSON payload and just reports whether the instance is concrete and valid;
+// Overlay provides a working set of in-memory files (relative entries are
+// resolved against dir); TimeoutMs bounds how long evaluation is allowed to
+// run before it's reported as timed out.
+type evalOptions struct {
+	ValidateOnly bool              `json:"validateOnly"`
+	Overlay      map[string]string `json:"overlay"`
+	TimeoutMs    int               `json:"timeoutMs"`
+}
+
+// diagnostic is a single evaluation problem, keeping the door open for
+// structured fields (position, code) without another ABI break.
+type diagnostic struct {
+	Message string `json:"message"`
+}
+
+// evalResult is the typed envelope cue_eval_package_ex returns, so callers
+// can distinguish "evaluation ran and found the instance invalid" from "I/O
+// or internal failure" without string-matching the JSON payload.
+type evalResult struct {
+	Status      string       `json:"status"`
+	JSON        string       `json:"json,omitempty"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+func errResult(message string) evalResult {
+	return evalResult{Status: "error", Diagnostics: []diagnostic{{Message: message}}}
+}
+
+func diagnosticsFromErr(err error) []diagnostic {
+	var diags []diagnostic
+	for _, e := range cueerrors.Errors(err) {
+		diags = append(diags, diagnostic{Message: e.Error()})
+	}
+	if len(diags) == 0 {
+		diags = []diagnostic{{Message: err.Error()}}
+	}
+	return diags
+}
+
+// buildCueValue loads the named package out of an already-configured
+// load.Config and builds it into a cue.Value. It's the single load/build
+// path shared by evalPackage and the streaming cue_eval_open API, so both
+// report the same errors for the same inputs.
+func buildCueValue(pkgName string, cfg *load.Config) (cue.Value, error) {
+	packagePath := ".:" + pkgName
+	instances := load.Instances([]string{packagePath}, cfg)
+
+	if len(instances) == 0 {
+		return cue.Value{}, fmt.Errorf("No CUE instances found")
+	}
+
+	inst := instances[0]
+	if inst.Err != nil {
+		return cue.Value{}, fmt.Errorf("Failed to load CUE instance: %v", inst.Err)
+	}
+
+	ctx := cuecontext.New()
+	v := ctx.BuildInstance(inst)
+	if v.Err() != nil {
+		return cue.Value{}, fmt.Errorf("Failed to build CUE value: %v", v.Err())
+	}
+
+	return v, nil
+}
+
+// resolveDirAndConfig validates dir/pkgName and turns dir into an absolute
+// load.Config, the same validation evalPackage and cue_eval_open both need
+// before they can call buildCueValue.
+func resolveDirAndConfig(dir, pkgName string) (string, *load.Config, error) {
+	if dir == "" {
+		return "", nil, fmt.Errorf("Directory path cannot be empty")
+	}
+	if pkgName == "" {
+		return "", nil, fmt.Errorf("Package name cannot be empty")
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to resolve directory %s: %v", dir, err)
+	}
+	if info, statErr := os.Stat(absDir); statErr != nil || !info.IsDir() {
+		return "", nil, fmt.Errorf("Failed to access directory %s: %v", absDir, statErr)
+	}
+
+	return absDir, &load.Config{Dir: absDir}, nil
+}
+
+// evalPackage loads and builds a CUE package entirely by absolute path -
+// never via os.Chdir - so concurrent callers pointed at different
+// directories never race over process-global working-directory state.
+// Imports are resolved relative to dir through load.Config.Dir.
+func evalPackage(dir, pkgName string, opts evalOptions) evalResult {
+	absDir, cfg, err := resolveDirAndConfig(dir, pkgName)
+	if err != nil {
+		return errResult(err.Error())
+	}
+
+	if len(opts.Overlay) > 0 {
+		cfg.Overlay = make(map[string]load.Source, len(opts.Overlay))
+		for path, content := range opts.Overlay {
+			overlayPath := path
+			if !filepath.IsAbs(overlayPath) {
+				overlayPath = filepath.Join(absDir, overlayPath)
+			}
+			cfg.Overlay[overlayPath] = load.FromString(content)
+		}
+	}
+
+	run := func() evalResult {
+		v, err := buildCueValue(pkgName, cfg)
+		if err != nil {
+			return errResult(err.Error())
+		}
+
+		if opts.ValidateOnly {
+			if err := v.Validate(cue.Concrete(true)); err != nil {
+				return evalResult{Status: "invalid", Diagnostics: diagnosticsFromErr(err)}
+			}
+			return evalResult{Status: "ok", Diagnostics: []diagnostic{}}
+		}
+
+		jsonStr, err := buildOrderedJSONString(v)
+		if err != nil {
+			return errResult(fmt.Sprintf("Failed to build ordered JSON: %v", err))
+		}
+
+		return evalResult{Status: "ok", JSON: jsonStr, Diagnostics: []diagnostic{}}
+	}
+
+	if opts.TimeoutMs <= 0 {
+		return run()
+	}
+
+	resultCh := make(chan evalResult, 1)
+	go func() { resultCh <- run() }()
+
+	select {
+	case res := <-resultCh:
+		return res
+	case <-time.After(time.Duration(opts.TimeoutMs) * time.Millisecond):
+		return errResult(fmt.Sprintf("Evaluation timed out after %dms", opts.TimeoutMs))
+	}
+}
+
 //export cue_eval_package
 func cue_eval_package(dirPath *C.char, packageName *C.char) *C.char {
 	// Add recover to catch any panics
@@ -37,85 +186,277 @@ func cue_eval_package(dirPath *C.char, packageName *C.char) *C.char {
 	goDir := C.GoString(dirPath)
 	goPackageName := C.GoString(packageName)
 
-	// Validate inputs
-	if goDir == "" {
-		errMsg := map[string]string{"error": "Directory path cannot be empty"}
+	res := evalPackage(goDir, goPackageName, evalOptions{})
+	if res.Status != "ok" {
+		message := "Internal error"
+		if len(res.Diagnostics) > 0 {
+			message = res.Diagnostics[0].Message
+		}
+		errMsg := map[string]string{"error": message}
 		errBytes, _ := json.Marshal(errMsg)
 		result = C.CString(string(errBytes))
 		return result
 	}
 
-	if goPackageName == "" {
-		errMsg := map[string]string{"error": "Package name cannot be empty"}
-		errBytes, _ := json.Marshal(errMsg)
-		result = C.CString(string(errBytes))
-		return result
+	result = C.CString(res.JSON)
+	return result
+}
+
+// cue_eval_package_ex is cue_eval_package's reentrant, richer sibling: it
+// never touches the process's working directory, accepts an options_json
+// blob (validate-only mode, an in-memory overlay working set, a timeout),
+// and returns a typed {status, json, diagnostics[]} envelope instead of a
+// bare string so callers don't have to string-match to tell an evaluation
+// error apart from an I/O failure.
+//
+//export cue_eval_package_ex
+func cue_eval_package_ex(dirPath *C.char, packageName *C.char, optionsJSON *C.char) *C.char {
+	var result *C.char
+	defer func() {
+		if r := recover(); r != nil {
+			errBytes, _ := json.Marshal(errResult(fmt.Sprintf("Internal error: %v", r)))
+			result = C.CString(string(errBytes))
+		}
+	}()
+
+	goDir := C.GoString(dirPath)
+	goPackageName := C.GoString(packageName)
+	goOptionsJSON := C.GoString(optionsJSON)
+
+	var opts evalOptions
+	if goOptionsJSON != "" {
+		if err := json.Unmarshal([]byte(goOptionsJSON), &opts); err != nil {
+			errBytes, _ := json.Marshal(errResult(fmt.Sprintf("Failed to parse options: %v", err)))
+			result = C.CString(string(errBytes))
+			return result
+		}
 	}
 
-	// Change to the specified directory
-	originalDir, err := os.Getwd()
+	res := evalPackage(goDir, goPackageName, opts)
+
+	resBytes, err := json.Marshal(res)
 	if err != nil {
-		errMsg := map[string]string{"error": fmt.Sprintf("Failed to get current directory: %v", err)}
-		errBytes, _ := json.Marshal(errMsg)
+		errBytes, _ := json.Marshal(errResult(fmt.Sprintf("Failed to encode result: %v", err)))
 		result = C.CString(string(errBytes))
 		return result
 	}
-	defer os.Chdir(originalDir) // Always restore original directory
 
-	if err := os.Chdir(goDir); err != nil {
-		errMsg := map[string]string{"error": fmt.Sprintf("Failed to change directory to %s: %v", goDir, err)}
-		errBytes, _ := json.Marshal(errMsg)
-		result = C.CString(string(errBytes))
-		return result
+	result = C.CString(string(resBytes))
+	return result
+}
+
+// evalSession holds the pre-rendered NDJSON events for one cue_eval_open
+// call. Events are computed once up front (the same load/build path as
+// evalPackage) and then handed out one at a time by cue_eval_next, so a
+// caller never has to hold the whole JSON document - or the whole event
+// list - twice.
+type evalSession struct {
+	mu     sync.Mutex
+	events []string
+	pos    int
+}
+
+var (
+	sessionsMu sync.Mutex
+	sessions   = map[int64]*evalSession{}
+	nextHandle int64
+)
+
+// eventJSON marshals a single NDJSON event, falling back to a diagnostic
+// event if the value itself can't be encoded (e.g. an un-decodable CUE
+// value slipped through).
+func eventJSON(m map[string]interface{}) string {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return `{"kind":"diagnostic","message":"failed to encode event"}`
 	}
+	return string(b)
+}
 
-	// Create CUE context
-	ctx := cuecontext.New()
+// buildEvalEvents loads and builds dir/pkgName exactly as evalPackage does,
+// then walks the result emitting NDJSON events in CUE source order: an
+// env_kv event per leaf under "env", a task_begin/task_end pair around each
+// entry under "tasks" (nested around its children when that entry is itself
+// a group of tasks), a diagnostic event on any load/build error, and a
+// trailing eof event always last.
+func buildEvalEvents(dir, pkgName string) []string {
+	var events []string
 
-	// Load the specific CUE package by name
-	// This matches the behavior of "cue export .:package-name"
-	var instances []*build.Instance
-	packagePath := ".:" + goPackageName
-	instances = load.Instances([]string{packagePath}, nil)
+	absDir, cfg, err := resolveDirAndConfig(dir, pkgName)
+	if err != nil {
+		events = append(events, eventJSON(map[string]interface{}{"kind": "diagnostic", "message": err.Error()}))
+		events = append(events, eventJSON(map[string]interface{}{"kind": "eof"}))
+		return events
+	}
+	_ = absDir
 
-	if len(instances) == 0 {
-		errMsg := map[string]string{"error": "No CUE instances found"}
-		errBytes, _ := json.Marshal(errMsg)
-		result = C.CString(string(errBytes))
-		return result
+	v, err := buildCueValue(pkgName, cfg)
+	if err != nil {
+		events = append(events, eventJSON(map[string]interface{}{"kind": "diagnostic", "message": err.Error()}))
+		events = append(events, eventJSON(map[string]interface{}{"kind": "eof"}))
+		return events
 	}
 
-	inst := instances[0]
-	if inst.Err != nil {
-		errMsg := map[string]string{"error": fmt.Sprintf("Failed to load CUE instance: %v", inst.Err)}
-		errBytes, _ := json.Marshal(errMsg)
-		result = C.CString(string(errBytes))
-		return result
+	if envField := v.LookupPath(cue.ParsePath("env")); envField.Exists() {
+		emitEnvKVEvents(envField, &events, "")
+	}
+	if tasksField := v.LookupPath(cue.ParsePath("tasks")); tasksField.Exists() {
+		emitTaskEvents(tasksField, &events, "tasks")
 	}
 
-	// Build the CUE value
-	v := ctx.BuildInstance(inst)
-	if v.Err() != nil {
-		errMsg := map[string]string{"error": fmt.Sprintf("Failed to build CUE value: %v", v.Err())}
-		errBytes, _ := json.Marshal(errMsg)
-		result = C.CString(string(errBytes))
-		return result
+	events = append(events, eventJSON(map[string]interface{}{"kind": "eof"}))
+	return events
+}
+
+// emitEnvKVEvents recurses into nested env structs, emitting one env_kv
+// event per leaf value in field order. Nested keys are joined with "." (e.g.
+// "DATABASE.HOST") so two leaves with the same name under different groups
+// stream as distinct keys instead of colliding.
+func emitEnvKVEvents(v cue.Value, events *[]string, prefix string) {
+	fields, err := v.Fields(cue.Optional(true))
+	if err != nil {
+		*events = append(*events, eventJSON(map[string]interface{}{"kind": "diagnostic", "message": err.Error()}))
+		return
+	}
+
+	for fields.Next() {
+		name := fields.Label()
+		val := fields.Value()
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		if val.Kind() == cue.StructKind {
+			emitEnvKVEvents(val, events, key)
+			continue
+		}
+
+		var decoded interface{}
+		if err := val.Decode(&decoded); err != nil {
+			*events = append(*events, eventJSON(map[string]interface{}{"kind": "diagnostic", "message": fmt.Sprintf("failed to decode %s: %v", key, err)}))
+			continue
+		}
+
+		*events = append(*events, eventJSON(map[string]interface{}{"kind": "env_kv", "key": key, "value": decoded}))
 	}
+}
 
-	// Build JSON manually by iterating through CUE fields in order
-	// This completely bypasses Go's map randomization
-	jsonStr, err := buildOrderedJSONString(v)
+// isTaskLeaf reports whether v is a task definition rather than a group of
+// tasks: per libcue-bridge/schema.go's TaskSchema, "command" and "script" are the only
+// fields that name an actual action to run, so their presence is what
+// distinguishes a task from a group (a group may still carry descriptive
+// fields like "description" or "mode").
+func isTaskLeaf(v cue.Value) bool {
+	return v.LookupPath(cue.ParsePath("command")).Exists() || v.LookupPath(cue.ParsePath("script")).Exists()
+}
+
+// emitTaskEvents walks a tasks struct in field order, emitting a
+// task_begin/task_end pair for every entry. Entries that are themselves a
+// group of tasks (rather than a task) have their children emitted between
+// the begin and end events, so ordered_group.a always precedes
+// ordered_group.b in the stream.
+func emitTaskEvents(v cue.Value, events *[]string, path string) {
+	fields, err := v.Fields(cue.Optional(true))
 	if err != nil {
-		errMsg := map[string]string{"error": fmt.Sprintf("Failed to build ordered JSON: %v", err)}
-		errBytes, _ := json.Marshal(errMsg)
-		result = C.CString(string(errBytes))
-		return result
+		*events = append(*events, eventJSON(map[string]interface{}{"kind": "diagnostic", "message": err.Error()}))
+		return
 	}
-	
-	jsonBytes := []byte(jsonStr)
 
-	result = C.CString(string(jsonBytes))
-	return result
+	for fields.Next() {
+		name := fields.Label()
+		val := fields.Value()
+
+		// Only struct-valued entries are tasks or groups of tasks; a group's
+		// own descriptive fields (e.g. "description", "mode") aren't.
+		if val.Kind() != cue.StructKind {
+			continue
+		}
+
+		childPath := path + "." + name
+		*events = append(*events, eventJSON(map[string]interface{}{"kind": "task_begin", "path": childPath}))
+		if !isTaskLeaf(val) {
+			emitTaskEvents(val, events, childPath)
+		}
+		*events = append(*events, eventJSON(map[string]interface{}{"kind": "task_end"}))
+	}
+}
+
+// safeBuildEvalEvents wraps buildEvalEvents with the same panic-to-diagnostic
+// recovery every other exported entry point applies, so a malformed CUE tree
+// can never crash the host process across the FFI boundary.
+func safeBuildEvalEvents(dir, pkgName string) (events []string) {
+	defer func() {
+		if r := recover(); r != nil {
+			events = []string{
+				eventJSON(map[string]interface{}{"kind": "diagnostic", "message": fmt.Sprintf("Internal error: %v", r)}),
+				eventJSON(map[string]interface{}{"kind": "eof"}),
+			}
+		}
+	}()
+	return buildEvalEvents(dir, pkgName)
+}
+
+// cue_eval_open evaluates dir/pkgName up front and registers the resulting
+// NDJSON event stream under a handle for cue_eval_next to drain. This lets a
+// caller apply env vars, register tasks, or write to $GITHUB_ENV
+// incrementally, in CUE source order, without ever holding the whole
+// document in memory twice. The caller owns the handle and must release it
+// with cue_eval_close, including on early-exit/error paths.
+//
+//export cue_eval_open
+func cue_eval_open(dirPath *C.char, packageName *C.char) C.longlong {
+	goDir := C.GoString(dirPath)
+	goPackageName := C.GoString(packageName)
+
+	events := safeBuildEvalEvents(goDir, goPackageName)
+
+	sessionsMu.Lock()
+	nextHandle++
+	handle := nextHandle
+	sessions[handle] = &evalSession{events: events}
+	sessionsMu.Unlock()
+
+	return C.longlong(handle)
+}
+
+// cue_eval_next writes the next NDJSON event for handle into *buf (owned by
+// the caller; free it with cue_free_string) and its length into *length.
+// Returns 0 when an event was written, 1 once the stream is exhausted (the
+// eof event has already been delivered), or -1 if handle is unknown.
+//
+//export cue_eval_next
+func cue_eval_next(handle C.longlong, buf **C.char, length *C.int) C.int {
+	sessionsMu.Lock()
+	sess, ok := sessions[int64(handle)]
+	sessionsMu.Unlock()
+	if !ok {
+		return -1
+	}
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if sess.pos >= len(sess.events) {
+		return 1
+	}
+
+	event := sess.events[sess.pos]
+	sess.pos++
+
+	*buf = C.CString(event)
+	*length = C.int(len(event))
+	return 0
+}
+
+// cue_eval_close discards the session for handle. Safe to call more than
+// once, or with a handle that's already been closed.
+//
+//export cue_eval_close
+func cue_eval_close(handle C.longlong) {
+	sessionsMu.Lock()
+	delete(sessions, int64(handle))
+	sessionsMu.Unlock()
 }
 
 // buildOrderedJSONString manually builds a JSON string from CUE value preserving field order
@@ -123,44 +464,51 @@ func buildOrderedJSONString(v cue.Value) (string, error) {
 	switch v.Kind() {
 	case cue.StructKind:
 		var parts []string
-		
+
 		// Iterate through fields in the order they appear in CUE
 		fields, err := v.Fields(cue.Optional(true))
 		if err != nil {
 			return "", fmt.Errorf("failed to get fields: %v", err)
 		}
-		
+
 		for fields.Next() {
 			fieldName := fields.Label()
 			fieldValue := fields.Value()
-			
+
 			// Build JSON key
 			keyJSON, err := json.Marshal(fieldName)
 			if err != nil {
 				return "", fmt.Errorf("failed to marshal field name %s: %v", fieldName, err)
 			}
-			
-			// Recursively build value JSON
-			valueJSON, err := buildOrderedJSONString(fieldValue)
+
+			// assertions/policies are evaluated rather than passed through
+			// raw, so the caller gets pass/fail results instead of just the
+			// `must` constraints themselves.
+			var valueJSON string
+			if fieldName == "assertions" || fieldName == "policies" {
+				valueJSON, err = buildAssertionsJSON(fieldValue)
+			} else {
+				valueJSON, err = buildOrderedJSONString(fieldValue)
+			}
 			if err != nil {
 				return "", fmt.Errorf("failed to build JSON for field %s: %v", fieldName, err)
 			}
-			
+
 			// Combine key:value
 			parts = append(parts, string(keyJSON)+":"+valueJSON)
 		}
-		
+
 		return "{" + strings.Join(parts, ",") + "}", nil
-		
+
 	case cue.ListKind:
 		var parts []string
-		
+
 		// Iterate through list items
 		list, err := v.List()
 		if err != nil {
 			return "", fmt.Errorf("failed to get list: %v", err)
 		}
-		
+
 		for list.Next() {
 			itemJSON, err := buildOrderedJSONString(list.Value())
 			if err != nil {
@@ -168,23 +516,102 @@ func buildOrderedJSONString(v cue.Value) (string, error) {
 			}
 			parts = append(parts, itemJSON)
 		}
-		
+
 		return "[" + strings.Join(parts, ",") + "]", nil
-		
+
 	default:
 		// For primitive types, use standard JSON marshaling
 		var val interface{}
 		if err := v.Decode(&val); err != nil {
 			return "", fmt.Errorf("failed to decode primitive value: %v", err)
 		}
-		
+
 		jsonBytes, err := json.Marshal(val)
 		if err != nil {
 			return "", fmt.Errorf("failed to marshal primitive value: %v", err)
 		}
-		
+
 		return string(jsonBytes), nil
 	}
 }
 
-func main() {}
\ No newline at end of file
+// buildAssertionsJSON evaluates each entry of an `assertions` (or
+// `policies`) list against the already-built env - a CUE constraint or small
+// predicate under `must`, e.g. `{name: "port in range", must: env.PORT >= 1024 & env.PORT <= 65535}` -
+// and renders the `{name, ok, message, path}` result array in CUE source
+// order. A failing assertion does not abort evaluation; it's up to the
+// caller (the Rust core, or `cuenv check`) to decide whether to treat it as
+// fatal.
+func buildAssertionsJSON(v cue.Value) (string, error) {
+	list, err := v.List()
+	if err != nil {
+		return "", fmt.Errorf("failed to get assertions list: %v", err)
+	}
+
+	var parts []string
+	for list.Next() {
+		entry := list.Value()
+
+		var name string
+		if nameField := entry.LookupPath(cue.ParsePath("name")); nameField.Exists() {
+			_ = nameField.Decode(&name)
+		}
+
+		var path string
+		if pathField := entry.LookupPath(cue.ParsePath("path")); pathField.Exists() {
+			_ = pathField.Decode(&path)
+		}
+
+		mustField := entry.LookupPath(cue.ParsePath("must"))
+		ok := true
+		message := ""
+		if !mustField.Exists() {
+			ok = false
+			message = "assertion has no 'must' constraint"
+		} else if err := mustField.Validate(cue.Concrete(true)); err != nil {
+			// Validate only catches a CUE-level conflict (bottom), e.g. a
+			// conjunction of contradictory constraints. A `must` that's simply
+			// a concrete boolean expression (`env.PORT >= 9999`) validates
+			// fine even when it evaluates to false, so that case is checked
+			// separately below.
+			ok = false
+			message = err.Error()
+		} else if boolResult, decodeErr := mustField.Bool(); decodeErr == nil && !boolResult {
+			ok = false
+			message = "assertion failed"
+		}
+
+		itemJSON, err := buildOrderedAssertionJSON(name, ok, message, path)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, itemJSON)
+	}
+
+	return "[" + strings.Join(parts, ",") + "]", nil
+}
+
+// buildOrderedAssertionJSON renders a single assertion result with a fixed
+// {name, ok, message, path} field order, matching the documented shape
+// rather than whatever order json.Marshal would pick for a map.
+func buildOrderedAssertionJSON(name string, ok bool, message, path string) (string, error) {
+	fieldNames := []string{"name", "ok", "message", "path"}
+	fieldValues := []interface{}{name, ok, message, path}
+
+	var parts []string
+	for i, fieldName := range fieldNames {
+		keyJSON, err := json.Marshal(fieldName)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal assertion field name %s: %v", fieldName, err)
+		}
+		valJSON, err := json.Marshal(fieldValues[i])
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal assertion field %s: %v", fieldName, err)
+		}
+		parts = append(parts, string(keyJSON)+":"+string(valJSON))
+	}
+
+	return "{" + strings.Join(parts, ",") + "}", nil
+}
+
+func main() {}
@@ -163,8 +163,8 @@ func TestCueEvalPackage_NonexistentDirectory(t *testing.T) {
 		t.Fatalf("Failed to parse error JSON: %v\nResult: %s", err, result)
 	}
 
-	if !strings.Contains(errorResponse["error"], "Failed to change directory") {
-		t.Errorf("Expected directory change error, got: %s", errorResponse["error"])
+	if !strings.Contains(errorResponse["error"], "Failed to access directory") {
+		t.Errorf("Expected directory access error, got: %s", errorResponse["error"])
 	}
 }
 
@@ -297,12 +297,30 @@ func TestCueEvalPackage_MemoryManagement(t *testing.T) {
 }
 
 func TestCueEvalPackage_ConcurrentAccess(t *testing.T) {
-	// Test concurrent calls to ensure thread safety
-	cueContent := `env: { CONCURRENT_VAR: "test" }`
-	tempDir, cleanup := createTestCueDir(t, "cuenv", cueContent)
-	defer cleanup()
-
+	// Test concurrent calls against *different* directories to stress the
+	// guarantee that evaluation never mutates process-global state (it used
+	// to os.Chdir into the target directory, which would race here).
 	const numGoroutines = 5
+
+	type job struct {
+		dir     string
+		want    string
+		cleanup func()
+	}
+
+	jobs := make([]job, numGoroutines)
+	for i := 0; i < numGoroutines; i++ {
+		want := fmt.Sprintf("value-%d", i)
+		dir, cleanup := createTestCueDir(t, "cuenv", fmt.Sprintf(`env: { CONCURRENT_VAR: %q }`, want))
+		jobs[i] = job{dir: dir, want: want, cleanup: cleanup}
+	}
+	defer func() {
+		for _, j := range jobs {
+			j.cleanup()
+		}
+	}()
+	seen := make(map[string]bool, numGoroutines)
+
 	results := make(chan string, numGoroutines)
 	errors := make(chan error, numGoroutines)
 
@@ -315,8 +333,7 @@ func TestCueEvalPackage_ConcurrentAccess(t *testing.T) {
 				}
 			}()
 
-			result := callCueEvalPackage(tempDir, "cuenv")
-			results <- result
+			results <- callCueEvalPackage(jobs[id].dir, "cuenv")
 		}(i)
 	}
 
@@ -336,9 +353,28 @@ func TestCueEvalPackage_ConcurrentAccess(t *testing.T) {
 				continue
 			}
 
-			if env["CONCURRENT_VAR"] != "test" {
-				t.Errorf("Concurrent call %d: expected CONCURRENT_VAR='test', got %v", i, env["CONCURRENT_VAR"])
+			// We don't know which goroutine produced this particular result,
+			// but every result must match one of the distinct per-directory
+			// values, and no value may come back twice. If chdir races were
+			// still in play, a result could instead reflect a *different*
+			// goroutine's directory, which leaves that directory's value
+			// duplicated (two goroutines that exactly swap directories are
+			// not caught by this check).
+			got, _ := env["CONCURRENT_VAR"].(string)
+			found := false
+			for _, j := range jobs {
+				if j.want == got {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Errorf("Concurrent call %d: CONCURRENT_VAR=%v does not match any expected per-directory value", i, env["CONCURRENT_VAR"])
 			}
+			if seen[got] {
+				t.Errorf("Concurrent call %d: CONCURRENT_VAR=%v was returned for more than one directory", i, env["CONCURRENT_VAR"])
+			}
+			seen[got] = true
 
 		case err := <-errors:
 			t.Errorf("Concurrent access error: %v", err)
@@ -346,6 +382,131 @@ func TestCueEvalPackage_ConcurrentAccess(t *testing.T) {
 	}
 }
 
+// Helper to call the cue_eval_package_ex FFI function safely.
+func callCueEvalPackageEx(dirPath, packageName, optionsJSON string) string {
+	cDirPath := C.CString(dirPath)
+	cPackageName := C.CString(packageName)
+	cOptionsJSON := C.CString(optionsJSON)
+	defer C.free(unsafe.Pointer(cDirPath))
+	defer C.free(unsafe.Pointer(cPackageName))
+	defer C.free(unsafe.Pointer(cOptionsJSON))
+
+	result := cue_eval_package_ex(cDirPath, cPackageName, cOptionsJSON)
+	defer cue_free_string(result)
+
+	return C.GoString(result)
+}
+
+func TestCueEvalPackageEx_StructuredStatus(t *testing.T) {
+	tempDir, cleanup := createTestCueDir(t, "cuenv", `env: { TEST_VAR: "value" }`)
+	defer cleanup()
+
+	result := callCueEvalPackageEx(tempDir, "cuenv", "")
+
+	var envelope struct {
+		Status      string                   `json:"status"`
+		JSON        string                   `json:"json"`
+		Diagnostics []map[string]interface{} `json:"diagnostics"`
+	}
+	if err := json.Unmarshal([]byte(result), &envelope); err != nil {
+		t.Fatalf("Failed to parse envelope: %v\nResult: %s", err, result)
+	}
+
+	if envelope.Status != "ok" {
+		t.Fatalf("Expected status 'ok', got %q (diagnostics: %v)", envelope.Status, envelope.Diagnostics)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(envelope.JSON), &data); err != nil {
+		t.Fatalf("Failed to parse embedded JSON: %v", err)
+	}
+	env, _ := data["env"].(map[string]interface{})
+	if env["TEST_VAR"] != "value" {
+		t.Errorf("Expected TEST_VAR='value', got %v", env["TEST_VAR"])
+	}
+}
+
+func TestCueEvalPackageEx_DistinguishesErrorFromInvalid(t *testing.T) {
+	// A directory that does not exist at all is an I/O-ish error.
+	result := callCueEvalPackageEx("/nonexistent/path", "cuenv", "")
+	var envelope struct {
+		Status      string `json:"status"`
+		Diagnostics []struct {
+			Message string `json:"message"`
+		} `json:"diagnostics"`
+	}
+	if err := json.Unmarshal([]byte(result), &envelope); err != nil {
+		t.Fatalf("Failed to parse envelope: %v\nResult: %s", err, result)
+	}
+	if envelope.Status != "error" {
+		t.Errorf("Expected status 'error' for a missing directory, got %q", envelope.Status)
+	}
+
+	// A well-formed but incomplete instance under validate-only mode is
+	// "invalid", not "error" - the caller can tell these apart without
+	// string-matching the message.
+	tempDir, cleanup := createTestCueDir(t, "cuenv", `env: { PORT: int }`)
+	defer cleanup()
+
+	result = callCueEvalPackageEx(tempDir, "cuenv", `{"validateOnly": true}`)
+	if err := json.Unmarshal([]byte(result), &envelope); err != nil {
+		t.Fatalf("Failed to parse envelope: %v\nResult: %s", err, result)
+	}
+	if envelope.Status != "invalid" {
+		t.Errorf("Expected status 'invalid' for a non-concrete value under validateOnly, got %q", envelope.Status)
+	}
+	if len(envelope.Diagnostics) == 0 {
+		t.Errorf("Expected at least one diagnostic explaining why the value is invalid")
+	}
+}
+
+func TestCueEvalPackageEx_Overlay(t *testing.T) {
+	tempDir, cleanup := createTestCueDir(t, "cuenv", `env: { BASE_VAR: "base" }`)
+	defer cleanup()
+
+	result := callCueEvalPackageEx(tempDir, "cuenv", `{"overlay": {"env.cue": "package cuenv\n\nenv: { OVERLAY_VAR: \"overlay\" }"}}`)
+
+	var envelope struct {
+		Status string `json:"status"`
+		JSON   string `json:"json"`
+	}
+	if err := json.Unmarshal([]byte(result), &envelope); err != nil {
+		t.Fatalf("Failed to parse envelope: %v\nResult: %s", err, result)
+	}
+	if envelope.Status != "ok" {
+		t.Fatalf("Expected status 'ok', got %q", envelope.Status)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(envelope.JSON), &data); err != nil {
+		t.Fatalf("Failed to parse embedded JSON: %v", err)
+	}
+	env, _ := data["env"].(map[string]interface{})
+	if env["OVERLAY_VAR"] != "overlay" {
+		t.Errorf("Expected the in-memory overlay file to replace env.cue, got env: %v", env)
+	}
+}
+
+func TestCueEvalPackageEx_Timeout(t *testing.T) {
+	tempDir, cleanup := createTestCueDir(t, "cuenv", `env: { TEST_VAR: "value" }`)
+	defer cleanup()
+
+	// A 0ns-ish timeout is unrealistic to hit reliably against real CUE
+	// evaluation, so this only exercises that a positive timeout still
+	// succeeds for fast evaluation - the actual expiry path is covered by
+	// code review of evalPackage's select{}.
+	result := callCueEvalPackageEx(tempDir, "cuenv", `{"timeoutMs": 5000}`)
+	var envelope struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(result), &envelope); err != nil {
+		t.Fatalf("Failed to parse envelope: %v\nResult: %s", err, result)
+	}
+	if envelope.Status != "ok" {
+		t.Errorf("Expected status 'ok' for a generous timeout, got %q", envelope.Status)
+	}
+}
+
 func TestFieldOrderingPreservation(t *testing.T) {
 	// Create a CUE file with tasks in a specific order
 	cueContent := `
@@ -482,4 +643,293 @@ tasks: {
 	if !t.Failed() {
 		t.Logf("✓ Consistency test passed across %d iterations", len(allResults))
 	}
+}
+
+func TestCueEvalPackage_Assertions_PassAndFail(t *testing.T) {
+	cueContent := `
+env: {
+	DATABASE_URL: "postgres://localhost/mydb"
+	PORT: 3000
+}
+
+assertions: [
+	{name: "db url is postgres", must: env.DATABASE_URL =~ "^postgres://"},
+	{name: "port in range", must: env.PORT >= 1024 & env.PORT <= 65535},
+	{name: "port is not privileged", must: env.PORT >= 9999},
+]`
+
+	tempDir, cleanup := createTestCueDir(t, "cuenv", cueContent)
+	defer cleanup()
+
+	result := callCueEvalPackage(tempDir, "cuenv")
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(result), &data); err != nil {
+		t.Fatalf("Failed to parse JSON result: %v\nResult: %s", err, result)
+	}
+
+	assertions, ok := data["assertions"].([]interface{})
+	if !ok {
+		t.Fatalf("Expected assertions to be an array, got %T (%v)", data["assertions"], data["assertions"])
+	}
+	if len(assertions) != 3 {
+		t.Fatalf("Expected 3 assertions, got %d", len(assertions))
+	}
+
+	first, ok := assertions[0].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected assertion to be an object, got %T", assertions[0])
+	}
+	if first["name"] != "db url is postgres" || first["ok"] != true {
+		t.Errorf("Expected first assertion to pass, got %v", first)
+	}
+
+	second, _ := assertions[1].(map[string]interface{})
+	if second["name"] != "port in range" || second["ok"] != true {
+		t.Errorf("Expected second assertion to pass, got %v", second)
+	}
+
+	third, _ := assertions[2].(map[string]interface{})
+	if third["name"] != "port is not privileged" || third["ok"] != false {
+		t.Errorf("Expected third assertion to fail, got %v", third)
+	}
+	if msg, _ := third["message"].(string); msg == "" {
+		t.Errorf("Expected a non-empty failure message on the failing assertion")
+	}
+}
+
+func TestCueEvalPackage_Assertions_OrderMatchesSource(t *testing.T) {
+	cueContent := `
+env: { PORT: 3000 }
+
+assertions: [
+	{name: "first", must: env.PORT > 0},
+	{name: "second", must: env.PORT > 0},
+	{name: "third", must: env.PORT > 0},
+]`
+
+	tempDir, cleanup := createTestCueDir(t, "cuenv", cueContent)
+	defer cleanup()
+
+	result := callCueEvalPackage(tempDir, "cuenv")
+
+	expectedOrder := []string{"first", "second", "third"}
+	positions := make(map[string]int)
+	for _, name := range expectedOrder {
+		searchStr := `"name":"` + name + `"`
+		pos := strings.Index(result, searchStr)
+		if pos == -1 {
+			t.Fatalf("Assertion %q not found in JSON: %s", name, result)
+		}
+		positions[name] = pos
+	}
+
+	for i := 1; i < len(expectedOrder); i++ {
+		prev, curr := expectedOrder[i-1], expectedOrder[i]
+		if positions[prev] >= positions[curr] {
+			t.Errorf("Assertion ordering incorrect: %s (pos %d) should come before %s (pos %d)",
+				prev, positions[prev], curr, positions[curr])
+		}
+	}
+}
+
+// streamEvent is the subset of NDJSON event fields the streaming tests
+// inspect.
+type streamEvent struct {
+	Kind  string      `json:"kind"`
+	Key   string      `json:"key,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+	Path  string      `json:"path,omitempty"`
+}
+
+// drainEvalStream opens handle dir/pkgName and calls cue_eval_next until eof,
+// returning the reassembled event list.
+func drainEvalStream(t *testing.T, dirPath, packageName string) []streamEvent {
+	cDirPath := C.CString(dirPath)
+	cPackageName := C.CString(packageName)
+	defer C.free(unsafe.Pointer(cDirPath))
+	defer C.free(unsafe.Pointer(cPackageName))
+
+	handle := cue_eval_open(cDirPath, cPackageName)
+	defer cue_eval_close(handle)
+
+	var events []streamEvent
+	for {
+		var buf *C.char
+		var length C.int
+		status := cue_eval_next(handle, &buf, &length)
+		if status == -1 {
+			t.Fatalf("cue_eval_next: unknown handle %d", handle)
+		}
+		if status == 1 {
+			break
+		}
+
+		raw := C.GoStringN(buf, length)
+		C.free(unsafe.Pointer(buf))
+
+		var ev streamEvent
+		if err := json.Unmarshal([]byte(raw), &ev); err != nil {
+			t.Fatalf("Failed to parse event JSON %q: %v", raw, err)
+		}
+		events = append(events, ev)
+	}
+
+	return events
+}
+
+func TestCueEvalStream_ReassemblesEnvAndEof(t *testing.T) {
+	cueContent := `
+env: {
+	DATABASE_URL: "postgres://localhost/app"
+	PORT:         3000
+}`
+
+	tempDir, cleanup := createTestCueDir(t, "cuenv", cueContent)
+	defer cleanup()
+
+	events := drainEvalStream(t, tempDir, "cuenv")
+	if len(events) == 0 || events[len(events)-1].Kind != "eof" {
+		t.Fatalf("Expected stream to end with an eof event, got: %+v", events)
+	}
+
+	values := map[string]interface{}{}
+	for _, ev := range events {
+		if ev.Kind == "env_kv" {
+			values[ev.Key] = ev.Value
+		}
+	}
+
+	if values["DATABASE_URL"] != "postgres://localhost/app" {
+		t.Errorf("Expected DATABASE_URL to stream as 'postgres://localhost/app', got %v", values["DATABASE_URL"])
+	}
+	if port, ok := values["PORT"].(float64); !ok || port != 3000 {
+		t.Errorf("Expected PORT to stream as 3000, got %v (%T)", values["PORT"], values["PORT"])
+	}
+}
+
+func TestCueEvalStream_NestedEnvKeysDoNotCollide(t *testing.T) {
+	cueContent := `
+env: {
+	app: { PORT: 3000 }
+	db: { PORT: 5432 }
+}`
+
+	tempDir, cleanup := createTestCueDir(t, "cuenv", cueContent)
+	defer cleanup()
+
+	events := drainEvalStream(t, tempDir, "cuenv")
+
+	values := map[string]interface{}{}
+	for _, ev := range events {
+		if ev.Kind == "env_kv" {
+			values[ev.Key] = ev.Value
+		}
+	}
+
+	if port, ok := values["app.PORT"].(float64); !ok || port != 3000 {
+		t.Errorf("Expected app.PORT to stream as 3000, got %v (%T)", values["app.PORT"], values["app.PORT"])
+	}
+	if port, ok := values["db.PORT"].(float64); !ok || port != 5432 {
+		t.Errorf("Expected db.PORT to stream as 5432, got %v (%T)", values["db.PORT"], values["db.PORT"])
+	}
+}
+
+func TestCueEvalStream_TaskOrderMatchesSource(t *testing.T) {
+	// Same fixture as TestFieldOrderingPreservation, so the two APIs are
+	// held to the same ordering invariant.
+	cueContent := `
+tasks: {
+	ordered_group: {
+		description: "Test field ordering"
+		mode: "sequential"
+
+		first: {
+			command: "echo first"
+		}
+		second: {
+			command: "echo second"
+		}
+		third: {
+			command: "echo third"
+		}
+		fourth: {
+			command: "echo fourth"
+		}
+	}
+}`
+
+	tempDir, cleanup := createTestCueDir(t, "cuenv", cueContent)
+	defer cleanup()
+
+	events := drainEvalStream(t, tempDir, "cuenv")
+
+	var beginPaths []string
+	for _, ev := range events {
+		if ev.Kind == "task_begin" {
+			beginPaths = append(beginPaths, ev.Path)
+		}
+	}
+
+	expected := []string{
+		"tasks.ordered_group",
+		"tasks.ordered_group.first",
+		"tasks.ordered_group.second",
+		"tasks.ordered_group.third",
+		"tasks.ordered_group.fourth",
+	}
+	if len(beginPaths) != len(expected) {
+		t.Fatalf("Expected task_begin paths %v, got %v", expected, beginPaths)
+	}
+	for i, path := range expected {
+		if beginPaths[i] != path {
+			t.Errorf("Expected task_begin[%d] = %q, got %q", i, path, beginPaths[i])
+		}
+	}
+
+	// Every task_begin must be paired with a task_end, and nesting means the
+	// group's task_end comes only after all its children have closed.
+	depth := 0
+	for _, ev := range events {
+		switch ev.Kind {
+		case "task_begin":
+			depth++
+		case "task_end":
+			depth--
+			if depth < 0 {
+				t.Fatalf("Unbalanced task_end in stream: %+v", events)
+			}
+		}
+	}
+	if depth != 0 {
+		t.Errorf("Expected all task_begin/task_end pairs to balance, final depth %d", depth)
+	}
+}
+
+func TestCueEvalStream_InvalidHandle(t *testing.T) {
+	var buf *C.char
+	var length C.int
+	if status := cue_eval_next(C.longlong(987654321), &buf, &length); status != -1 {
+		t.Errorf("Expected -1 for an unknown handle, got %d", status)
+	}
+}
+
+func TestCueEvalStream_ClosePreventsFurtherReads(t *testing.T) {
+	cueContent := `env: { TEST_VAR: "value" }`
+	tempDir, cleanup := createTestCueDir(t, "cuenv", cueContent)
+	defer cleanup()
+
+	cDirPath := C.CString(tempDir)
+	cPackageName := C.CString("cuenv")
+	defer C.free(unsafe.Pointer(cDirPath))
+	defer C.free(unsafe.Pointer(cPackageName))
+
+	handle := cue_eval_open(cDirPath, cPackageName)
+	cue_eval_close(handle)
+
+	var buf *C.char
+	var length C.int
+	if status := cue_eval_next(handle, &buf, &length); status != -1 {
+		t.Errorf("Expected -1 after close, got %d", status)
+	}
 }
\ No newline at end of file
@@ -5,28 +5,312 @@ package main
 */
 import "C"
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
 	"unsafe"
 
 	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
 	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/cue/errors"
 	"cuelang.org/go/cue/load"
+	"cuelang.org/go/cue/parser"
 	"cuelang.org/go/mod/modconfig"
 )
 
-// extractSecretReference checks if a CUE value has a resolver field, indicating it's a secret
-func extractSecretReference(val cue.Value) string {
-	// Check if this value has a resolver field (indicates it's a secret type)
+// errorResult builds the {"error": ...} payload cue_eval_package/cue_eval_source
+// return on failure. When err carries CUE position information (a load or
+// build error does), the source file/line/column are included as their own
+// fields so downstream consumers - like the GitHub Actions runner mode -
+// can annotate the exact location instead of guessing from the message text.
+func errorResult(err error) map[string]interface{} {
+	result := map[string]interface{}{"error": err.Error()}
+	for _, e := range errors.Errors(err) {
+		pos := e.Position()
+		if pos.Filename() != "" {
+			result["file"] = pos.Filename()
+			result["line"] = pos.Line()
+			result["column"] = pos.Column()
+			break
+		}
+	}
+	return result
+}
+
+// unifyInputs compiles inputsJSON as a CUE value and unifies it into v, so
+// callers can parameterize an environment (region, stage, feature flags)
+// without writing sidecar CUE files. Schema violations come back as CUE
+// unification errors annotated with the offending field path.
+func unifyInputs(ctx *cue.Context, v cue.Value, inputsJSON string) (cue.Value, error) {
+	if inputsJSON == "" {
+		return v, nil
+	}
+
+	inputs := ctx.CompileString(inputsJSON)
+	if inputs.Err() != nil {
+		return v, fmt.Errorf("failed to compile inputs: %v", inputs.Err())
+	}
+
+	unified := v.Unify(inputs)
+	if err := unified.Validate(); err != nil {
+		var msgs []string
+		for _, e := range errors.Errors(err) {
+			path := strings.Join(e.Path(), ".")
+			if path == "" {
+				msgs = append(msgs, e.Error())
+			} else {
+				msgs = append(msgs, fmt.Sprintf("%s: %s", path, e.Error()))
+			}
+		}
+		return v, fmt.Errorf("inputs failed validation: %s", strings.Join(msgs, "; "))
+	}
+
+	return unified, nil
+}
+
+// secretResolverFields lists, per resolver kind, the CUE fields that must be
+// present under `resolver` for that kind to be serialized. Order matches the
+// field's natural identity (e.g. a path before the key within it).
+var secretResolverFields = map[string][]string{
+	"exec":   {"cmd", "args"},
+	"vault":  {"path", "field", "mount"},
+	"aws-sm": {"secretId", "version", "region"},
+	"gcp-sm": {"project", "secret", "version"},
+	"op":     {"vault", "item"},
+	"file":   {"path", "key"},
+}
+
+// cacheDescriptor is the structured shape of a task's `cache` block. A bare
+// `cache: true`/`cache: false` shorthand decodes to just Enabled. A
+// structured block defaults Enabled to true, Scope to "local" and
+// Compression to "none"; every other field is only set when the
+// corresponding CUE field is present.
+type cacheDescriptor struct {
+	Enabled     bool     `json:"enabled"`
+	Key         string   `json:"key,omitempty"`
+	Inputs      []string `json:"inputs,omitempty"`
+	Outputs     []string `json:"outputs,omitempty"`
+	TTL         string   `json:"ttl,omitempty"`
+	Scope       string   `json:"scope,omitempty"`
+	Compression string   `json:"compression,omitempty"`
+}
+
+// extractCacheDescriptor decodes a task's `cache` field, which is either the
+// legacy boolean shorthand or a structured descriptor with a content key,
+// input/output path lists, a TTL, a scope, and a compression mode.
+func extractCacheDescriptor(cacheField cue.Value) *cacheDescriptor {
+	var enabled bool
+	if err := cacheField.Decode(&enabled); err == nil {
+		return &cacheDescriptor{Enabled: enabled}
+	}
+
+	desc := &cacheDescriptor{Enabled: true, Scope: "local", Compression: "none"}
+
+	if f := cacheField.LookupPath(cue.ParsePath("enabled")); f.Exists() {
+		var v bool
+		if err := f.Decode(&v); err == nil {
+			desc.Enabled = v
+		}
+	}
+	if f := cacheField.LookupPath(cue.ParsePath("key")); f.Exists() {
+		var v string
+		if err := f.Decode(&v); err == nil {
+			desc.Key = v
+		}
+	}
+	if f := cacheField.LookupPath(cue.ParsePath("inputs")); f.Exists() {
+		var v []string
+		if err := f.Decode(&v); err == nil {
+			desc.Inputs = v
+		}
+	}
+	if f := cacheField.LookupPath(cue.ParsePath("outputs")); f.Exists() {
+		var v []string
+		if err := f.Decode(&v); err == nil {
+			desc.Outputs = v
+		}
+	}
+	if f := cacheField.LookupPath(cue.ParsePath("ttl")); f.Exists() {
+		var v string
+		if err := f.Decode(&v); err == nil {
+			desc.TTL = v
+		}
+	}
+	if f := cacheField.LookupPath(cue.ParsePath("scope")); f.Exists() {
+		var v string
+		if err := f.Decode(&v); err == nil {
+			desc.Scope = v
+		}
+	}
+	if f := cacheField.LookupPath(cue.ParsePath("compression")); f.Exists() {
+		var v string
+		if err := f.Decode(&v); err == nil {
+			desc.Compression = v
+		}
+	}
+
+	return desc
+}
+
+// computeTaskCacheDigest hashes the parts of a task that determine cache
+// validity - command/script, dependencies, declared inputs, the cache
+// descriptor's own key/inputs, the extracted environment variables, and the
+// Go toolchain version this bridge was built with - into a stable digest.
+// encoding/json sorts map keys when marshaling, so the same task always
+// produces the same bytes to hash regardless of map iteration order, giving
+// the Rust cache layer a content hash it doesn't have to derive itself.
+// Including resolvedEnv and the toolchain version means a changed env var,
+// secret resolver reference, or upgraded compiler invalidates the digest.
+// A resolver-backed secret contributes its cuenv-resolver:// reference, not
+// the value it resolves to at run time, so a change in that value alone
+// leaves the digest unchanged.
+func computeTaskCacheDigest(taskName string, taskConfig map[string]interface{}, resolvedEnv map[string]interface{}) string {
+	digestInput := map[string]interface{}{
+		"name":        taskName,
+		"resolvedEnv": resolvedEnv,
+		"toolVersion": runtime.Version(),
+	}
+	for _, field := range []string{"command", "script", "dependencies", "inputs", "workingDir", "shell"} {
+		if v, ok := taskConfig[field]; ok {
+			digestInput[field] = v
+		}
+	}
+	if cache, ok := taskConfig["cache"].(*cacheDescriptor); ok {
+		digestInput["cacheKey"] = cache.Key
+		digestInput["cacheInputs"] = cache.Inputs
+	}
+
+	jsonBytes, err := json.Marshal(digestInput)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(jsonBytes)
+	return hex.EncodeToString(sum[:])
+}
+
+// extractOverlays decodes a task's `security.overlays` list into the shape
+// the Rust executor stacks into an overlayfs mount: a read-only lowerDir, an
+// optional upperDir (an ephemeral tmpfs upper is created when omitted), an
+// optional workDir, and the mountPoint the stacked root is exposed at.
+// lowerDir and mountPoint are required - same as OverlaySchema (schema.go)
+// tags them - and an entry missing either errors instead of silently
+// serializing a mount spec with no source or destination, matching
+// extractSecretReference's required-field handling.
+func extractOverlays(overlaysField cue.Value) ([]map[string]interface{}, error) {
+	var overlays []map[string]interface{}
+
+	iter, err := overlaysField.List()
+	if err != nil {
+		return overlays, nil
+	}
+
+	for iter.Next() {
+		entry := iter.Value()
+		overlay := make(map[string]interface{})
+
+		lowerField := entry.LookupPath(cue.ParsePath("lowerDir"))
+		if !lowerField.Exists() {
+			return nil, fmt.Errorf("overlay requires lowerDir")
+		}
+		var lowerDir string
+		if err := lowerField.Decode(&lowerDir); err != nil {
+			return nil, fmt.Errorf("overlay lowerDir must be a string: %v", err)
+		}
+		overlay["lowerDir"] = lowerDir
+
+		mountField := entry.LookupPath(cue.ParsePath("mountPoint"))
+		if !mountField.Exists() {
+			return nil, fmt.Errorf("overlay requires mountPoint")
+		}
+		var mountPoint string
+		if err := mountField.Decode(&mountPoint); err != nil {
+			return nil, fmt.Errorf("overlay mountPoint must be a string: %v", err)
+		}
+		overlay["mountPoint"] = mountPoint
+
+		if upperField := entry.LookupPath(cue.ParsePath("upperDir")); upperField.Exists() {
+			var upperDir string
+			if err := upperField.Decode(&upperDir); err == nil {
+				overlay["upperDir"] = upperDir
+			}
+		}
+
+		if workField := entry.LookupPath(cue.ParsePath("workDir")); workField.Exists() {
+			var workDir string
+			if err := workField.Decode(&workDir); err == nil {
+				overlay["workDir"] = workDir
+			}
+		}
+
+		if roField := entry.LookupPath(cue.ParsePath("readOnly")); roField.Exists() {
+			var readOnly bool
+			if err := roField.Decode(&readOnly); err == nil {
+				overlay["readOnly"] = readOnly
+			}
+		}
+
+		overlays = append(overlays, overlay)
+	}
+
+	return overlays, nil
+}
+
+// extractSecretReference checks if a CUE value has a resolver field,
+// indicating it's a secret, and serializes it as a `cuenv-resolver://`
+// reference for the Rust side to decode and resolve at run time.
+//
+// Resolvers are selected via `resolver.kind` (exec, vault, aws-sm, gcp-sm, op,
+// file); the serialized form is tagged with that kind as a discriminator:
+// `cuenv-resolver://v2/{kind}/{json}`. A `resolver` block with no `kind` is
+// treated as the original cmd/args-only shape and kept on the legacy,
+// unversioned `cuenv-resolver://{json}` encoding for back-compat. Returns an
+// error describing any missing required field instead of silently dropping
+// the secret.
+func extractSecretReference(val cue.Value) (string, error) {
 	resolverField := val.LookupPath(cue.ParsePath("resolver"))
 	if !resolverField.Exists() {
-		return ""
+		return "", nil
+	}
+
+	kindField := resolverField.LookupPath(cue.ParsePath("kind"))
+	if !kindField.Exists() {
+		return extractLegacyExecResolver(resolverField)
+	}
+
+	var kind string
+	if err := kindField.Decode(&kind); err != nil {
+		return "", fmt.Errorf("resolver.kind must be a string: %v", err)
 	}
 
-	// Extract the resolver configuration
+	required, known := secretResolverFields[kind]
+	if !known {
+		return "", fmt.Errorf("unknown resolver kind %q", kind)
+	}
+
+	fields, err := decodeResolverFields(resolverField, kind, required)
+	if err != nil {
+		return "", err
+	}
+
+	jsonBytes, err := json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode resolver: %v", err)
+	}
+
+	return fmt.Sprintf("cuenv-resolver://v2/%s/%s", kind, string(jsonBytes)), nil
+}
+
+// extractLegacyExecResolver preserves the original cmd/args-only encoding for
+// resolver blocks that predate the `kind` discriminator.
+func extractLegacyExecResolver(resolverField cue.Value) (string, error) {
 	// Check for both "cmd" and "command" fields for compatibility
 	cmdField := resolverField.LookupPath(cue.ParsePath("cmd"))
 	if !cmdField.Exists() {
@@ -35,11 +319,9 @@ func extractSecretReference(val cue.Value) string {
 	argsField := resolverField.LookupPath(cue.ParsePath("args"))
 
 	if !cmdField.Exists() || !argsField.Exists() {
-		return ""
+		return "", fmt.Errorf("exec resolver requires both cmd and args")
 	}
 
-	// For now, we'll encode the resolver as a JSON string that the Rust side can decode
-	// In the future, this could be a more sophisticated encoding
 	type Resolver struct {
 		Cmd  string   `json:"cmd"`
 		Args []string `json:"args"`
@@ -47,7 +329,7 @@ func extractSecretReference(val cue.Value) string {
 
 	var cmd string
 	if err := cmdField.Decode(&cmd); err != nil {
-		return ""
+		return "", fmt.Errorf("resolver cmd must be a string: %v", err)
 	}
 
 	// Decode args array
@@ -68,10 +350,46 @@ func extractSecretReference(val cue.Value) string {
 	// Encode as JSON with a special prefix to identify it as a resolver
 	jsonBytes, err := json.Marshal(resolver)
 	if err != nil {
-		return ""
+		return "", fmt.Errorf("failed to encode resolver: %v", err)
 	}
 
-	return "cuenv-resolver://" + string(jsonBytes)
+	return "cuenv-resolver://" + string(jsonBytes), nil
+}
+
+// decodeResolverFields decodes the required fields for a typed resolver kind
+// out of the CUE resolver block, erroring if any of them is missing. `args`
+// is special-cased to decode as an ordered string list rather than a generic
+// interface{}, matching the exec resolver's existing shape.
+func decodeResolverFields(resolverField cue.Value, kind string, required []string) (map[string]interface{}, error) {
+	fields := make(map[string]interface{}, len(required))
+
+	for _, name := range required {
+		field := resolverField.LookupPath(cue.ParsePath(name))
+		if !field.Exists() {
+			return nil, fmt.Errorf("%s resolver requires field %q", kind, name)
+		}
+
+		if name == "args" {
+			iter, _ := field.List()
+			var args []string
+			for iter.Next() {
+				var arg string
+				if err := iter.Value().Decode(&arg); err == nil {
+					args = append(args, arg)
+				}
+			}
+			fields[name] = args
+			continue
+		}
+
+		var value interface{}
+		if err := field.Decode(&value); err != nil {
+			return nil, fmt.Errorf("%s resolver field %q: %v", kind, name, err)
+		}
+		fields[name] = value
+	}
+
+	return fields, nil
 }
 
 //export cue_free_string
@@ -80,7 +398,7 @@ func cue_free_string(s *C.char) {
 }
 
 //export cue_eval_package
-func cue_eval_package(dirPath *C.char, packageName *C.char) *C.char {
+func cue_eval_package(dirPath *C.char, packageName *C.char, inputsJSON *C.char) *C.char {
 	// Add recover to catch any panics
 	var result *C.char
 	defer func() {
@@ -94,6 +412,7 @@ func cue_eval_package(dirPath *C.char, packageName *C.char) *C.char {
 
 	goDir := C.GoString(dirPath)
 	goPkg := C.GoString(packageName)
+	goInputsJSON := C.GoString(inputsJSON)
 
 	// Validate inputs
 	if goDir == "" {
@@ -144,8 +463,7 @@ func cue_eval_package(dirPath *C.char, packageName *C.char) *C.char {
 	// Check for load errors
 	inst := instances[0]
 	if inst.Err != nil {
-		errMsg := map[string]string{"error": inst.Err.Error()}
-		errBytes, _ := json.Marshal(errMsg)
+		errBytes, _ := json.Marshal(errorResult(inst.Err))
 		result = C.CString(string(errBytes))
 		return result
 	}
@@ -155,7 +473,14 @@ func cue_eval_package(dirPath *C.char, packageName *C.char) *C.char {
 	v := ctx.BuildInstance(inst)
 
 	if v.Err() != nil {
-		errMsg := map[string]string{"error": v.Err().Error()}
+		errBytes, _ := json.Marshal(errorResult(v.Err()))
+		result = C.CString(string(errBytes))
+		return result
+	}
+
+	v, err = unifyInputs(ctx, v, goInputsJSON)
+	if err != nil {
+		errMsg := map[string]string{"error": err.Error()}
 		errBytes, _ := json.Marshal(errMsg)
 		result = C.CString(string(errBytes))
 		return result
@@ -177,6 +502,195 @@ func cue_eval_package(dirPath *C.char, packageName *C.char) *C.char {
 	return result
 }
 
+// mountImportRoot makes every file under root visible to the loader at the
+// same relative path under virtualDir, by adding it to cfg.Overlay. This is
+// how cue_eval_source honors more than one import root: every extra root is
+// stacked into the same virtual directory the in-memory source lives in, so
+// an import can resolve against any of them. Earlier roots win on a path
+// collision, matching the order the caller listed them in.
+func mountImportRoot(cfg *load.Config, root, virtualDir string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		overlayPath := filepath.Join(virtualDir, rel)
+		if _, exists := cfg.Overlay[overlayPath]; exists {
+			return nil
+		}
+		// virtualDir is the first root itself; an overlay entry would
+		// shadow its real file, so leave that file in place.
+		if _, statErr := os.Stat(overlayPath); statErr == nil {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		cfg.Overlay[overlayPath] = load.FromString(string(content))
+		return nil
+	})
+}
+
+// filePackageName returns the package name declared in a parsed CUE file,
+// or the empty string if the file has no package clause.
+func filePackageName(f *ast.File) string {
+	for _, decl := range f.Decls {
+		if pkg, ok := decl.(*ast.Package); ok && pkg.Name != nil {
+			return pkg.Name.Name
+		}
+	}
+	return ""
+}
+
+//export cue_eval_source
+func cue_eval_source(cueSource *C.char, importRootsJSON *C.char, inputsJSON *C.char) *C.char {
+	// Add recover to catch any panics
+	var result *C.char
+	defer func() {
+		if r := recover(); r != nil {
+			errMsg := map[string]string{"error": fmt.Sprintf("Internal error: %v", r)}
+			errBytes, _ := json.Marshal(errMsg)
+			result = C.CString(string(errBytes))
+		}
+	}()
+
+	goSource := C.GoString(cueSource)
+	goImportRootsJSON := C.GoString(importRootsJSON)
+	goInputsJSON := C.GoString(inputsJSON)
+
+	if goSource == "" {
+		errMsg := map[string]string{"error": "CUE source cannot be empty"}
+		errBytes, _ := json.Marshal(errMsg)
+		result = C.CString(string(errBytes))
+		return result
+	}
+
+	var importRoots []string
+	if goImportRootsJSON != "" {
+		if err := json.Unmarshal([]byte(goImportRootsJSON), &importRoots); err != nil {
+			errMsg := map[string]string{"error": "Failed to parse import roots: " + err.Error()}
+			errBytes, _ := json.Marshal(errMsg)
+			result = C.CString(string(errBytes))
+			return result
+		}
+	}
+
+	// Parse the source ourselves so the env-package restriction is enforced
+	// on the AST, before a load.Config (and its own package filtering) ever
+	// gets involved.
+	file, err := parser.ParseFile("source.cue", goSource, parser.ParseComments)
+	if err != nil {
+		errMsg := map[string]string{"error": "Failed to parse CUE source: " + err.Error()}
+		errBytes, _ := json.Marshal(errMsg)
+		result = C.CString(string(errBytes))
+		return result
+	}
+
+	if pkgName := filePackageName(file); pkgName != "" && pkgName != "env" {
+		errMsg := map[string]string{"error": fmt.Sprintf("Only 'env' package is supported, got '%s'. Please ensure your CUE source uses 'package env'", pkgName)}
+		errBytes, _ := json.Marshal(errMsg)
+		result = C.CString(string(errBytes))
+		return result
+	}
+
+	// Build a synthetic, in-memory directory so imports can still be resolved
+	// relative to the caller-supplied roots, without ever touching the real
+	// filesystem for the source itself.
+	virtualDir := "/cuenv-source"
+	if len(importRoots) > 0 {
+		virtualDir = importRoots[0]
+	}
+	overlayPath := filepath.Join(virtualDir, "source.cue")
+
+	registry, err := modconfig.NewRegistry(&modconfig.Config{
+		Env: os.Environ(),
+	})
+	if err != nil {
+		errMsg := map[string]string{"error": "Failed to create registry: " + err.Error()}
+		errBytes, _ := json.Marshal(errMsg)
+		result = C.CString(string(errBytes))
+		return result
+	}
+
+	cfg := &load.Config{
+		Dir:      virtualDir,
+		Package:  "env",
+		Registry: registry,
+		Env:      os.Environ(),
+		Overlay: map[string]load.Source{
+			overlayPath: load.FromString(goSource),
+		},
+	}
+
+	// Additional import roots (beyond the one used as virtualDir) are
+	// mounted into the same virtual directory so imports can resolve
+	// against any of them, not just the first.
+	if len(importRoots) > 1 {
+		for _, root := range importRoots[1:] {
+			if err := mountImportRoot(cfg, root, virtualDir); err != nil {
+				errMsg := map[string]string{"error": fmt.Sprintf("Failed to mount import root %s: %v", root, err)}
+				errBytes, _ := json.Marshal(errMsg)
+				result = C.CString(string(errBytes))
+				return result
+			}
+		}
+	}
+
+	instances := load.Instances([]string{overlayPath}, cfg)
+	if len(instances) == 0 {
+		errMsg := map[string]string{"error": "No CUE instances found in source"}
+		errBytes, _ := json.Marshal(errMsg)
+		result = C.CString(string(errBytes))
+		return result
+	}
+
+	inst := instances[0]
+	if inst.Err != nil {
+		errBytes, _ := json.Marshal(errorResult(inst.Err))
+		result = C.CString(string(errBytes))
+		return result
+	}
+
+	ctx := cuecontext.New()
+	v := ctx.BuildInstance(inst)
+	if v.Err() != nil {
+		errBytes, _ := json.Marshal(errorResult(v.Err()))
+		result = C.CString(string(errBytes))
+		return result
+	}
+
+	v, err = unifyInputs(ctx, v, goInputsJSON)
+	if err != nil {
+		errMsg := map[string]string{"error": err.Error()}
+		errBytes, _ := json.Marshal(errMsg)
+		result = C.CString(string(errBytes))
+		return result
+	}
+
+	data := extractCueData(v)
+
+	jsonBytes, err := json.Marshal(data)
+	if err != nil {
+		errMsg := map[string]string{"error": err.Error()}
+		errBytes, _ := json.Marshal(errMsg)
+		result = C.CString(string(errBytes))
+		return result
+	}
+
+	result = C.CString(string(jsonBytes))
+	return result
+}
+
 // extractCueData extracts the structured data from a CUE value
 func extractCueData(v cue.Value) map[string]interface{} {
 	result := map[string]interface{}{
@@ -216,7 +730,8 @@ func extractCueData(v cue.Value) map[string]interface{} {
 				varMeta := make(map[string]interface{})
 
 				for _, attr := range attrs {
-					if attr.Name() == "capability" {
+					switch attr.Name() {
+					case "capability":
 						if caps, err := attr.String(0); err == nil {
 							varMeta["capability"] = caps
 							// Also store in parent metadata if not already there
@@ -224,18 +739,31 @@ func extractCueData(v cue.Value) map[string]interface{} {
 								metadata[key] = map[string]interface{}{"capability": caps}
 							}
 						}
+					case "secret":
+						// @secret() marks a variable as sensitive for downstream
+						// consumers (e.g. the GitHub Actions runner mode) to mask.
+						varMeta["secret"] = true
+						if _, exists := metadata[key]; !exists {
+							metadata[key] = map[string]interface{}{"secret": true}
+						}
 					}
 				}
 
-				if len(varMeta) > 0 {
-					envMeta[key] = varMeta
-				}
-
 				// Check if this is a secret type
-				secretRef := extractSecretReference(val)
-				if secretRef != "" {
+				secretRef, secretErr := extractSecretReference(val)
+				switch {
+				case secretErr != nil:
+					varMeta["resolverError"] = secretErr.Error()
+					envMeta[key] = varMeta
+				case secretRef != "":
 					envVars[key] = secretRef
-				} else {
+					if len(varMeta) > 0 {
+						envMeta[key] = varMeta
+					}
+				default:
+					if len(varMeta) > 0 {
+						envMeta[key] = varMeta
+					}
 					// Regular value
 					var goVal interface{}
 					if err := val.Decode(&goVal); err == nil {
@@ -451,22 +979,23 @@ func extractCueData(v cue.Value) map[string]interface{} {
 					}
 				}
 
+				// Extract overlays - reproducible read-only task roots stacked
+				// with writable scratch dirs, the same contract as buildah's
+				// pkg/overlay.
+				if overlaysField := securityField.LookupPath(cue.ParsePath("overlays")); overlaysField.Exists() {
+					if overlays, err := extractOverlays(overlaysField); err != nil {
+						security["overlaysError"] = err.Error()
+					} else if len(overlays) > 0 {
+						security["overlays"] = overlays
+					}
+				}
+
 				taskConfig["security"] = security
 			}
 
-			// Extract cache - can be either a boolean or an object
+			// Extract cache - can be either a boolean or a structured descriptor
 			if cacheField := iter.Value().LookupPath(cue.ParsePath("cache")); cacheField.Exists() {
-				// Try to decode as boolean first (simple case)
-				var cacheBool bool
-				if err := cacheField.Decode(&cacheBool); err == nil {
-					taskConfig["cache"] = cacheBool
-				} else {
-					// Try to decode as an object (advanced case)
-					var cacheObj map[string]interface{}
-					if err := cacheField.Decode(&cacheObj); err == nil {
-						taskConfig["cache"] = cacheObj
-					}
-				}
+				taskConfig["cache"] = extractCacheDescriptor(cacheField)
 			}
 
 			// Extract cacheKey
@@ -509,6 +1038,32 @@ func extractCueData(v cue.Value) map[string]interface{} {
 		}
 	}
 
+	// Extract outputs configuration if present (top-level, not under env).
+	// This is what the GitHub Actions runner mode forwards to
+	// $GITHUB_OUTPUT.
+	if outputsField := v.LookupPath(cue.ParsePath("outputs")); outputsField.Exists() {
+		outputs := make(map[string]interface{})
+		iter, _ := outputsField.Fields()
+		for iter.Next() {
+			key := iter.Label()
+			val := iter.Value()
+
+			for _, attr := range val.Attributes(cue.ValueAttr) {
+				if attr.Name() == "secret" {
+					if _, exists := metadata[key]; !exists {
+						metadata[key] = map[string]interface{}{"secret": true}
+					}
+				}
+			}
+
+			var goVal interface{}
+			if err := val.Decode(&goVal); err == nil {
+				outputs[key] = goVal
+			}
+		}
+		result["outputs"] = outputs
+	}
+
 	// Extract variables with capability metadata
 	vars := result["variables"].(map[string]interface{})
 
@@ -523,26 +1078,35 @@ func extractCueData(v cue.Value) map[string]interface{} {
 			continue
 		}
 
-		// Extract attributes (like @capability)
+		// Extract attributes (like @capability and @secret)
 		attrs := val.Attributes(cue.ValueAttr)
 		varMeta := make(map[string]interface{})
 
 		for _, attr := range attrs {
-			if attr.Name() == "capability" {
+			switch attr.Name() {
+			case "capability":
 				if caps, err := attr.String(0); err == nil {
 					varMeta["capability"] = caps
 				}
+			case "secret":
+				// @secret() marks a variable as sensitive for downstream
+				// consumers (e.g. the GitHub Actions runner mode) to mask.
+				varMeta["secret"] = true
 			}
 		}
 
 		// Check if this is a secret type and convert accordingly
-		secretRef := extractSecretReference(val)
-		if secretRef != "" {
+		secretRef, secretErr := extractSecretReference(val)
+		switch {
+		case secretErr != nil:
+			varMeta["resolverError"] = secretErr.Error()
+			metadata[key] = varMeta
+		case secretRef != "":
 			vars[key] = secretRef
 			if len(varMeta) > 0 {
 				metadata[key] = varMeta
 			}
-		} else {
+		default:
 			// Convert CUE value to Go value
 			var goVal interface{}
 			if err := val.Decode(&goVal); err == nil {
@@ -554,6 +1118,22 @@ func extractCueData(v cue.Value) map[string]interface{} {
 		}
 	}
 
+	// Compute each cache-enabled task's digest now that the environment it
+	// runs with is fully resolved - the digest has to be computed after
+	// variable extraction, not inline in the tasks loop above, so it can
+	// cover resolvedEnv.
+	if tasks, ok := result["tasks"].(map[string]interface{}); ok {
+		for taskName, taskConfigRaw := range tasks {
+			taskConfig, ok := taskConfigRaw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if cache, ok := taskConfig["cache"].(*cacheDescriptor); ok && cache.Enabled {
+				taskConfig["cacheDigest"] = computeTaskCacheDigest(taskName, taskConfig, vars)
+			}
+		}
+	}
+
 	return result
 }
 
@@ -0,0 +1,151 @@
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// callCueEvalSource calls cue_eval_source with the given CUE source, import
+// roots (as a JSON array, or "" for none) and inputs, returning the result
+// as a Go string.
+func callCueEvalSource(t *testing.T, source, importRootsJSON, inputsJSON string) string {
+	t.Helper()
+
+	cSource := C.CString(source)
+	defer C.free(unsafe.Pointer(cSource))
+	cImportRoots := C.CString(importRootsJSON)
+	defer C.free(unsafe.Pointer(cImportRoots))
+	cInputs := C.CString(inputsJSON)
+	defer C.free(unsafe.Pointer(cInputs))
+
+	result := cue_eval_source(cSource, cImportRoots, cInputs)
+	defer cue_free_string(result)
+
+	return C.GoString(result)
+}
+
+func writeImportRoot(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestCueEvalSource_NoImportRoots(t *testing.T) {
+	out := callCueEvalSource(t, `package env
+
+env: {
+	PORT: "8080"
+}`, "", "")
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &data); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v, raw: %s", err, out)
+	}
+	if _, hasErr := data["error"]; hasErr {
+		t.Fatalf("Expected no error, got: %s", out)
+	}
+	vars, _ := data["variables"].(map[string]interface{})
+	if vars["PORT"] != "8080" {
+		t.Errorf("Expected PORT=8080, got variables: %v", vars)
+	}
+}
+
+func TestCueEvalSource_SingleImportRoot(t *testing.T) {
+	root := writeImportRoot(t, map[string]string{
+		"lib/consts.cue": `package lib
+
+Port: "9090"
+`,
+	})
+	importRoots, _ := json.Marshal([]string{root})
+
+	out := callCueEvalSource(t, `package env
+
+import "lib"
+
+env: {
+	PORT: lib.Port
+}`, string(importRoots), "")
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &data); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v, raw: %s", err, out)
+	}
+	if _, hasErr := data["error"]; hasErr {
+		t.Fatalf("Expected the import to resolve against the single root, got: %s", out)
+	}
+	vars, _ := data["variables"].(map[string]interface{})
+	if vars["PORT"] != "9090" {
+		t.Errorf("Expected PORT=9090, got variables: %v", vars)
+	}
+}
+
+func TestCueEvalSource_MultipleImportRoots(t *testing.T) {
+	rootA := writeImportRoot(t, map[string]string{
+		"a/a.cue": `package a
+
+Name: "from-a"
+`,
+	})
+	rootB := writeImportRoot(t, map[string]string{
+		"b/b.cue": `package b
+
+Name: "from-b"
+`,
+	})
+	importRoots, _ := json.Marshal([]string{rootA, rootB})
+
+	out := callCueEvalSource(t, `package env
+
+import (
+	"a"
+	"b"
+)
+
+env: {
+	FIRST: a.Name
+	SECOND: b.Name
+}`, string(importRoots), "")
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &data); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v, raw: %s", err, out)
+	}
+	if _, hasErr := data["error"]; hasErr {
+		t.Fatalf("Expected both import roots to resolve, got: %s", out)
+	}
+	vars, _ := data["variables"].(map[string]interface{})
+	if vars["FIRST"] != "from-a" || vars["SECOND"] != "from-b" {
+		t.Errorf("Expected FIRST=from-a and SECOND=from-b, got variables: %v", vars)
+	}
+}
+
+func TestCueEvalSource_RejectsNonEnvPackage(t *testing.T) {
+	out := callCueEvalSource(t, `package other
+
+env: {
+	PORT: "8080"
+}`, "", "")
+
+	if !strings.Contains(out, "Only 'env' package is supported") {
+		t.Errorf("Expected a package-name rejection error, got: %s", out)
+	}
+}
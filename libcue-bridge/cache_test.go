@@ -0,0 +1,159 @@
+package main
+
+import (
+	"testing"
+
+	"cuelang.org/go/cue/cuecontext"
+)
+
+func TestExtractCacheDescriptor_BooleanShorthand(t *testing.T) {
+	ctx := cuecontext.New()
+
+	v := ctx.CompileString(`true`)
+	desc := extractCacheDescriptor(v)
+	if desc == nil || !desc.Enabled {
+		t.Errorf("Expected cache: true to decode to Enabled: true, got %+v", desc)
+	}
+
+	v = ctx.CompileString(`false`)
+	desc = extractCacheDescriptor(v)
+	if desc == nil || desc.Enabled {
+		t.Errorf("Expected cache: false to decode to Enabled: false, got %+v", desc)
+	}
+}
+
+func TestExtractCacheDescriptor_StructuredFields(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`{
+		key: "build-cache"
+		inputs: ["src/**", "go.mod"]
+		outputs: ["dist/"]
+		ttl: "24h"
+		scope: "shared"
+		compression: "zstd"
+	}`)
+
+	desc := extractCacheDescriptor(v)
+	if !desc.Enabled {
+		t.Error("Expected a structured cache block to default Enabled to true")
+	}
+	if desc.Key != "build-cache" {
+		t.Errorf("Expected Key %q, got %q", "build-cache", desc.Key)
+	}
+	if len(desc.Inputs) != 2 || desc.Inputs[0] != "src/**" || desc.Inputs[1] != "go.mod" {
+		t.Errorf("Expected Inputs [src/** go.mod], got %v", desc.Inputs)
+	}
+	if len(desc.Outputs) != 1 || desc.Outputs[0] != "dist/" {
+		t.Errorf("Expected Outputs [dist/], got %v", desc.Outputs)
+	}
+	if desc.TTL != "24h" {
+		t.Errorf("Expected TTL %q, got %q", "24h", desc.TTL)
+	}
+	if desc.Scope != "shared" {
+		t.Errorf("Expected Scope %q, got %q", "shared", desc.Scope)
+	}
+	if desc.Compression != "zstd" {
+		t.Errorf("Expected Compression %q, got %q", "zstd", desc.Compression)
+	}
+}
+
+func TestExtractCacheDescriptor_StructuredDefaultsWhenFieldsOmitted(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`{key: "k"}`)
+
+	desc := extractCacheDescriptor(v)
+	if desc.Scope != "local" {
+		t.Errorf("Expected default Scope %q, got %q", "local", desc.Scope)
+	}
+	if desc.Compression != "none" {
+		t.Errorf("Expected default Compression %q, got %q", "none", desc.Compression)
+	}
+}
+
+func TestExtractCacheDescriptor_StructuredEnabledOverride(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`{enabled: false, key: "k"}`)
+
+	desc := extractCacheDescriptor(v)
+	if desc.Enabled {
+		t.Error("Expected an explicit enabled: false to override the structured-block default")
+	}
+}
+
+func TestComputeTaskCacheDigest_DeterministicForSameInput(t *testing.T) {
+	taskConfig := map[string]interface{}{
+		"command":      "go build ./...",
+		"dependencies": []interface{}{"lint"},
+		"inputs":       []interface{}{"src/**"},
+		"workingDir":   "/app",
+		"shell":        "bash",
+		"cache":        &cacheDescriptor{Key: "k", Inputs: []string{"src/**"}},
+	}
+	resolvedEnv := map[string]interface{}{"PORT": "8080"}
+
+	digest1 := computeTaskCacheDigest("build", taskConfig, resolvedEnv)
+	digest2 := computeTaskCacheDigest("build", taskConfig, resolvedEnv)
+
+	if digest1 == "" {
+		t.Fatal("Expected a non-empty digest")
+	}
+	if digest1 != digest2 {
+		t.Errorf("Expected the same task config to produce the same digest, got %q and %q", digest1, digest2)
+	}
+}
+
+func TestComputeTaskCacheDigest_DiffersWhenConfigChanges(t *testing.T) {
+	base := map[string]interface{}{
+		"command": "go build ./...",
+	}
+	changed := map[string]interface{}{
+		"command": "go build -race ./...",
+	}
+
+	digestBase := computeTaskCacheDigest("build", base, nil)
+	digestChanged := computeTaskCacheDigest("build", changed, nil)
+
+	if digestBase == digestChanged {
+		t.Errorf("Expected different commands to produce different digests, both got %q", digestBase)
+	}
+}
+
+func TestComputeTaskCacheDigest_DiffersWhenTaskNameChanges(t *testing.T) {
+	taskConfig := map[string]interface{}{"command": "go test ./..."}
+
+	digestA := computeTaskCacheDigest("test-unit", taskConfig, nil)
+	digestB := computeTaskCacheDigest("test-integration", taskConfig, nil)
+
+	if digestA == digestB {
+		t.Errorf("Expected different task names to produce different digests, both got %q", digestA)
+	}
+}
+
+func TestComputeTaskCacheDigest_IgnoresMapIterationOrder(t *testing.T) {
+	configA := map[string]interface{}{
+		"command":      "go build ./...",
+		"dependencies": []interface{}{"lint", "generate"},
+	}
+	configB := map[string]interface{}{
+		"dependencies": []interface{}{"lint", "generate"},
+		"command":      "go build ./...",
+	}
+
+	digestA := computeTaskCacheDigest("build", configA, nil)
+	digestB := computeTaskCacheDigest("build", configB, nil)
+
+	if digestA != digestB {
+		t.Errorf("Expected map field insertion order not to affect the digest, got %q and %q", digestA, digestB)
+	}
+}
+
+func TestComputeTaskCacheDigest_DiffersWhenResolvedEnvChanges(t *testing.T) {
+	taskConfig := map[string]interface{}{"command": "go build ./..."}
+
+	digestA := computeTaskCacheDigest("build", taskConfig, map[string]interface{}{"API_KEY": "old-secret"})
+	digestB := computeTaskCacheDigest("build", taskConfig, map[string]interface{}{"API_KEY": "new-secret"})
+
+	if digestA == digestB {
+		t.Errorf("Expected a changed resolved env value to change the digest, both got %q", digestA)
+	}
+}
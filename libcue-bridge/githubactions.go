@@ -0,0 +1,240 @@
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"unsafe"
+)
+
+//export cue_free_github_actions_result
+func cue_free_github_actions_result(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
+
+// cue_apply_github_actions takes the JSON already produced by
+// cue_eval_package (or cue_eval_source) and, when running inside GitHub
+// Actions (GITHUB_ACTIONS=true), turns it into workflow-command side
+// effects: every top-level variable is appended to $GITHUB_ENV (a nested
+// struct or list value is written as one JSON-encoded value; its leaves are
+// not written as separate variables), the "path"
+// variable (if set) is appended to $GITHUB_PATH, and the top-level "outputs"
+// map is appended to $GITHUB_OUTPUT - all using the randomized heredoc
+// delimiter form so multi-line values survive. Variables whose CUE
+// declaration carries a `@secret()` attribute are additionally masked with
+// `::add-mask::` before being written anywhere. Outside of GitHub Actions
+// this is a no-op.
+//
+//export cue_apply_github_actions
+func cue_apply_github_actions(jsonData *C.char) *C.char {
+	var result *C.char
+	defer func() {
+		if r := recover(); r != nil {
+			errMsg := map[string]string{"error": fmt.Sprintf("Internal error: %v", r)}
+			errBytes, _ := json.Marshal(errMsg)
+			result = C.CString(string(errBytes))
+		}
+	}()
+
+	if os.Getenv("GITHUB_ACTIONS") != "true" {
+		okBytes, _ := json.Marshal(map[string]interface{}{"applied": false, "reason": "not running inside GitHub Actions"})
+		result = C.CString(string(okBytes))
+		return result
+	}
+
+	goJSON := C.GoString(jsonData)
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(goJSON), &data); err != nil {
+		errMsg := map[string]string{"error": "Failed to parse evaluation JSON: " + err.Error()}
+		errBytes, _ := json.Marshal(errMsg)
+		result = C.CString(string(errBytes))
+		return result
+	}
+
+	if errMsg, hasErr := data["error"].(string); hasErr {
+		emitGitHubActionsError(errMsg, data["file"], data["line"])
+		errBytes, _ := json.Marshal(map[string]string{"error": errMsg})
+		result = C.CString(string(errBytes))
+		return result
+	}
+
+	fmt.Println("::group::cuenv")
+	defer fmt.Println("::endgroup::")
+
+	if err := applyGitHubActionsEnv(data); err != nil {
+		errMsg := map[string]string{"error": err.Error()}
+		errBytes, _ := json.Marshal(errMsg)
+		result = C.CString(string(errBytes))
+		return result
+	}
+
+	okBytes, _ := json.Marshal(map[string]interface{}{"applied": true})
+	result = C.CString(string(okBytes))
+	return result
+}
+
+// applyGitHubActionsEnv writes the evaluated variables/outputs to the
+// workflow-command files GitHub Actions points at via GITHUB_ENV,
+// GITHUB_PATH and GITHUB_OUTPUT.
+func applyGitHubActionsEnv(data map[string]interface{}) error {
+	variables, _ := data["variables"].(map[string]interface{})
+	metadata, _ := data["metadata"].(map[string]interface{})
+	outputs, _ := data["outputs"].(map[string]interface{})
+
+	githubEnv := os.Getenv("GITHUB_ENV")
+	githubPath := os.Getenv("GITHUB_PATH")
+	githubOutput := os.Getenv("GITHUB_OUTPUT")
+
+	for name, value := range variables {
+		if name == "path" {
+			continue // routed to GITHUB_PATH below instead
+		}
+		if isSecretVar(metadata, name) {
+			maskValue(value)
+		}
+		if githubEnv == "" {
+			continue
+		}
+		if err := appendWorkflowCommand(githubEnv, name, value); err != nil {
+			return fmt.Errorf("failed to write %s to GITHUB_ENV: %w", name, err)
+		}
+	}
+
+	if pathValue, ok := variables["path"]; ok && githubPath != "" {
+		for _, entry := range toStringList(pathValue) {
+			if err := appendLine(githubPath, entry); err != nil {
+				return fmt.Errorf("failed to write to GITHUB_PATH: %w", err)
+			}
+		}
+	}
+
+	if githubOutput != "" {
+		for name, value := range outputs {
+			if isSecretVar(metadata, name) {
+				maskValue(value)
+			}
+			if err := appendWorkflowCommand(githubOutput, name, value); err != nil {
+				return fmt.Errorf("failed to write output %s to GITHUB_OUTPUT: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// isSecretVar reports whether a variable was declared with a `@secret()`
+// attribute, as recorded by extractCueData in the metadata map.
+func isSecretVar(metadata map[string]interface{}, name string) bool {
+	meta, ok := metadata[name].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	secret, _ := meta["secret"].(bool)
+	return secret
+}
+
+// maskValue echoes `::add-mask::<value>` so the Actions runner scrubs the
+// value from subsequent log output.
+func maskValue(value interface{}) {
+	fmt.Printf("::add-mask::%s\n", stringifyValue(value))
+}
+
+// appendWorkflowCommand appends a NAME<<DELIM / value / DELIM block to file,
+// using a randomly generated delimiter so values containing newlines (or the
+// delimiter itself) round-trip safely.
+func appendWorkflowCommand(file, name string, value interface{}) error {
+	delim, err := randomDelimiter()
+	if err != nil {
+		return err
+	}
+
+	block := fmt.Sprintf("%s<<%s\n%s\n%s\n", name, delim, stringifyValue(value), delim)
+	return appendToFile(file, block)
+}
+
+// appendLine appends a single line to file, used for $GITHUB_PATH entries.
+func appendLine(file, line string) error {
+	return appendToFile(file, line+"\n")
+}
+
+func appendToFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(content)
+	return err
+}
+
+// randomDelimiter generates a heredoc delimiter that is vanishingly unlikely
+// to collide with real variable content.
+func randomDelimiter() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate delimiter: %w", err)
+	}
+	return "ghadelim_" + hex.EncodeToString(buf), nil
+}
+
+// stringifyValue renders a value for a workflow-command file: strings pass
+// through untouched, everything else is JSON-encoded.
+func stringifyValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	jsonBytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(jsonBytes)
+}
+
+// toStringList normalizes a $GITHUB_PATH entry, which may be a single string
+// or a list of strings.
+func toStringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// emitGitHubActionsError routes a CUE evaluation error through the
+// `::error file=...,line=...::message` workflow command so it surfaces as an
+// annotation on the triggering file. file/line come from the "file"/"line"
+// fields errorResult (libcue-bridge/bridge.go) adds to the evaluation JSON
+// from the failing error's own CUE position - not parsed out of the message
+// text, which carries no reliable file:line:col prefix - falling back to
+// env.cue/line 1 when a position wasn't available (e.g. a non-CUE I/O
+// error).
+func emitGitHubActionsError(message string, file, line interface{}) {
+	fileName, ok := file.(string)
+	if !ok || fileName == "" {
+		fileName = "env.cue"
+	}
+
+	lineNumber := 1
+	if l, ok := line.(float64); ok && l > 0 {
+		lineNumber = int(l)
+	}
+
+	fmt.Printf("::error file=%s,line=%d::%s\n", fileName, lineNumber, message)
+}
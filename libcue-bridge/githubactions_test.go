@@ -0,0 +1,210 @@
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// callCueApplyGitHubActions calls cue_apply_github_actions with jsonData and
+// returns its result as a Go string.
+func callCueApplyGitHubActions(t *testing.T, jsonData string) string {
+	t.Helper()
+
+	cJSON := C.CString(jsonData)
+	defer C.free(unsafe.Pointer(cJSON))
+
+	result := cue_apply_github_actions(cJSON)
+	defer cue_free_github_actions_result(result)
+
+	return C.GoString(result)
+}
+
+// captureStdout redirects os.Stdout for the duration of fn and returns what
+// it wrote, so tests can assert on the `::add-mask::`/`::error ...::`
+// workflow commands these functions print.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+
+	fn()
+
+	w.Close()
+	os.Stdout = orig
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to read captured stdout: %v", err)
+	}
+	return string(out)
+}
+
+func TestApplyGitHubActionsEnv_WritesVariablesToGithubEnv(t *testing.T) {
+	dir := t.TempDir()
+	githubEnv := filepath.Join(dir, "github_env")
+	if err := os.WriteFile(githubEnv, nil, 0644); err != nil {
+		t.Fatalf("Failed to create GITHUB_ENV file: %v", err)
+	}
+	t.Setenv("GITHUB_ENV", githubEnv)
+	t.Setenv("GITHUB_PATH", "")
+	t.Setenv("GITHUB_OUTPUT", "")
+
+	data := map[string]interface{}{
+		"variables": map[string]interface{}{
+			"DATABASE_URL": "postgres://localhost/app",
+		},
+		"metadata": map[string]interface{}{},
+	}
+
+	if err := applyGitHubActionsEnv(data); err != nil {
+		t.Fatalf("applyGitHubActionsEnv failed: %v", err)
+	}
+
+	content, err := os.ReadFile(githubEnv)
+	if err != nil {
+		t.Fatalf("Failed to read GITHUB_ENV file: %v", err)
+	}
+
+	if !strings.Contains(string(content), "DATABASE_URL<<") {
+		t.Errorf("Expected a DATABASE_URL<<DELIM heredoc block, got:\n%s", content)
+	}
+	if !strings.Contains(string(content), "postgres://localhost/app") {
+		t.Errorf("Expected the value to be written, got:\n%s", content)
+	}
+}
+
+func TestApplyGitHubActionsEnv_RoutesPathToGithubPath(t *testing.T) {
+	dir := t.TempDir()
+	githubPath := filepath.Join(dir, "github_path")
+	if err := os.WriteFile(githubPath, nil, 0644); err != nil {
+		t.Fatalf("Failed to create GITHUB_PATH file: %v", err)
+	}
+	t.Setenv("GITHUB_ENV", "")
+	t.Setenv("GITHUB_PATH", githubPath)
+	t.Setenv("GITHUB_OUTPUT", "")
+
+	data := map[string]interface{}{
+		"variables": map[string]interface{}{
+			"path": []interface{}{"/usr/local/bin", "/opt/tool/bin"},
+		},
+		"metadata": map[string]interface{}{},
+	}
+
+	if err := applyGitHubActionsEnv(data); err != nil {
+		t.Fatalf("applyGitHubActionsEnv failed: %v", err)
+	}
+
+	content, err := os.ReadFile(githubPath)
+	if err != nil {
+		t.Fatalf("Failed to read GITHUB_PATH file: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 || lines[0] != "/usr/local/bin" || lines[1] != "/opt/tool/bin" {
+		t.Errorf("Expected two path entries in order, got: %v", lines)
+	}
+}
+
+func TestApplyGitHubActionsEnv_WritesOutputsToGithubOutput(t *testing.T) {
+	dir := t.TempDir()
+	githubOutput := filepath.Join(dir, "github_output")
+	if err := os.WriteFile(githubOutput, nil, 0644); err != nil {
+		t.Fatalf("Failed to create GITHUB_OUTPUT file: %v", err)
+	}
+	t.Setenv("GITHUB_ENV", "")
+	t.Setenv("GITHUB_PATH", "")
+	t.Setenv("GITHUB_OUTPUT", githubOutput)
+
+	data := map[string]interface{}{
+		"variables": map[string]interface{}{},
+		"metadata":  map[string]interface{}{},
+		"outputs": map[string]interface{}{
+			"image_tag": "v1.2.3",
+		},
+	}
+
+	if err := applyGitHubActionsEnv(data); err != nil {
+		t.Fatalf("applyGitHubActionsEnv failed: %v", err)
+	}
+
+	content, err := os.ReadFile(githubOutput)
+	if err != nil {
+		t.Fatalf("Failed to read GITHUB_OUTPUT file: %v", err)
+	}
+
+	if !strings.Contains(string(content), "image_tag<<") || !strings.Contains(string(content), "v1.2.3") {
+		t.Errorf("Expected image_tag=v1.2.3 written as a heredoc block, got:\n%s", content)
+	}
+}
+
+func TestApplyGitHubActionsEnv_MasksSecretVariables(t *testing.T) {
+	dir := t.TempDir()
+	githubEnv := filepath.Join(dir, "github_env")
+	if err := os.WriteFile(githubEnv, nil, 0644); err != nil {
+		t.Fatalf("Failed to create GITHUB_ENV file: %v", err)
+	}
+	t.Setenv("GITHUB_ENV", githubEnv)
+	t.Setenv("GITHUB_PATH", "")
+	t.Setenv("GITHUB_OUTPUT", "")
+
+	data := map[string]interface{}{
+		"variables": map[string]interface{}{
+			"API_KEY": "super-secret-value",
+		},
+		"metadata": map[string]interface{}{
+			"API_KEY": map[string]interface{}{"secret": true},
+		},
+	}
+
+	out := captureStdout(t, func() {
+		if err := applyGitHubActionsEnv(data); err != nil {
+			t.Fatalf("applyGitHubActionsEnv failed: %v", err)
+		}
+	})
+
+	if !strings.Contains(out, "::add-mask::super-secret-value") {
+		t.Errorf("Expected API_KEY to be masked via ::add-mask::, got stdout:\n%s", out)
+	}
+}
+
+func TestEmitGitHubActionsError_UsesPositionWhenAvailable(t *testing.T) {
+	out := captureStdout(t, func() {
+		emitGitHubActionsError("conflicting values int and string", "env.cue", float64(12))
+	})
+
+	if !strings.Contains(out, "::error file=env.cue,line=12::conflicting values int and string") {
+		t.Errorf("Expected an annotation using the supplied file/line, got: %s", out)
+	}
+}
+
+func TestEmitGitHubActionsError_FallsBackWithoutPosition(t *testing.T) {
+	out := captureStdout(t, func() {
+		emitGitHubActionsError("some internal error", nil, nil)
+	})
+
+	if !strings.Contains(out, "::error file=env.cue,line=1::some internal error") {
+		t.Errorf("Expected the env.cue/line 1 fallback, got: %s", out)
+	}
+}
+
+func TestCueApplyGithubActions_OutsideGithubActionsIsNoop(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "")
+
+	result := callCueApplyGitHubActions(t, `{"variables":{}}`)
+	if !strings.Contains(result, `"applied":false`) {
+		t.Errorf("Expected a no-op result outside GitHub Actions, got: %s", result)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"cuelang.org/go/cue/cuecontext"
+)
+
+func TestUnifyInputs_SuccessfulUnify(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`env: PORT: string`)
+
+	unified, err := unifyInputs(ctx, v, `{env: {PORT: "8080"}}`)
+	if err != nil {
+		t.Fatalf("unifyInputs failed: %v", err)
+	}
+
+	var goVal struct {
+		Env struct {
+			Port string `json:"PORT"`
+		} `json:"env"`
+	}
+	if err := unified.Decode(&goVal); err != nil {
+		t.Fatalf("Failed to decode unified value: %v", err)
+	}
+	if goVal.Env.Port != "8080" {
+		t.Errorf("Expected PORT=8080, got %q", goVal.Env.Port)
+	}
+}
+
+func TestUnifyInputs_PathAnnotatedValidationFailure(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`env: PORT: int`)
+
+	_, err := unifyInputs(ctx, v, `{env: {PORT: "not-an-int"}}`)
+	if err == nil {
+		t.Fatal("Expected a validation error for a type mismatch")
+	}
+	if !strings.Contains(err.Error(), "PORT") {
+		t.Errorf("Expected the error to be annotated with the offending path, got: %v", err)
+	}
+}
+
+func TestUnifyInputs_MalformedJSONCompileFailure(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`env: PORT: string`)
+
+	_, err := unifyInputs(ctx, v, `{not valid json`)
+	if err == nil {
+		t.Fatal("Expected a compile error for malformed inputs JSON")
+	}
+	if !strings.Contains(err.Error(), "failed to compile inputs") {
+		t.Errorf("Expected a compile-failure error, got: %v", err)
+	}
+}
+
+func TestUnifyInputs_EmptyInputsIsNoop(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`env: PORT: "8080"`)
+
+	unified, err := unifyInputs(ctx, v, "")
+	if err != nil {
+		t.Fatalf("unifyInputs failed: %v", err)
+	}
+	if !unified.Equals(v) {
+		t.Error("Expected empty inputs to return the value unchanged")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"cuelang.org/go/cue/cuecontext"
+)
+
+func TestExtractOverlays_FullEntry(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`[{
+		lowerDir: "/app/base"
+		upperDir: "/app/upper"
+		workDir: "/app/work"
+		mountPoint: "/app/root"
+		readOnly: true
+	}]`)
+
+	overlays, err := extractOverlays(v)
+	if err != nil {
+		t.Fatalf("extractOverlays failed: %v", err)
+	}
+	if len(overlays) != 1 {
+		t.Fatalf("Expected one overlay, got %d", len(overlays))
+	}
+
+	overlay := overlays[0]
+	if overlay["lowerDir"] != "/app/base" {
+		t.Errorf("Expected lowerDir %q, got %v", "/app/base", overlay["lowerDir"])
+	}
+	if overlay["upperDir"] != "/app/upper" {
+		t.Errorf("Expected upperDir %q, got %v", "/app/upper", overlay["upperDir"])
+	}
+	if overlay["workDir"] != "/app/work" {
+		t.Errorf("Expected workDir %q, got %v", "/app/work", overlay["workDir"])
+	}
+	if overlay["mountPoint"] != "/app/root" {
+		t.Errorf("Expected mountPoint %q, got %v", "/app/root", overlay["mountPoint"])
+	}
+	if overlay["readOnly"] != true {
+		t.Errorf("Expected readOnly true, got %v", overlay["readOnly"])
+	}
+}
+
+func TestExtractOverlays_OnlyRequiredFields(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`[{lowerDir: "/app/base", mountPoint: "/app/root"}]`)
+
+	overlays, err := extractOverlays(v)
+	if err != nil {
+		t.Fatalf("extractOverlays failed: %v", err)
+	}
+	if len(overlays) != 1 {
+		t.Fatalf("Expected one overlay, got %d", len(overlays))
+	}
+	if _, ok := overlays[0]["upperDir"]; ok {
+		t.Error("Expected no upperDir entry when omitted")
+	}
+}
+
+func TestExtractOverlays_MissingLowerDirErrors(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`[{readOnly: true}]`)
+
+	_, err := extractOverlays(v)
+	if err == nil || !strings.Contains(err.Error(), "lowerDir") {
+		t.Errorf("Expected an error naming lowerDir, got %v", err)
+	}
+}
+
+func TestExtractOverlays_MissingMountPointErrors(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`[{lowerDir: "/app/base"}]`)
+
+	_, err := extractOverlays(v)
+	if err == nil || !strings.Contains(err.Error(), "mountPoint") {
+		t.Errorf("Expected an error naming mountPoint, got %v", err)
+	}
+}
+
+func TestExtractOverlays_EmptyList(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`[]`)
+
+	overlays, err := extractOverlays(v)
+	if err != nil {
+		t.Fatalf("extractOverlays failed: %v", err)
+	}
+	if len(overlays) != 0 {
+		t.Errorf("Expected no overlays, got %v", overlays)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// schemaModuleFS embeds the cuenv.dev/schema CUE module checked into
+// schemamod/ (schemamod/cue.mod/module.cue plus schemamod/schema.cue), the
+// same #Task/#Security/#Overlay/#Cache/#Hook/#Env definitions emitCueSchema
+// generates, committed so user CUE files can `import "cuenv.dev/schema"`
+// instead of regenerating it. TestEmbeddedSchemaMatchesGenerated guards
+// against the two drifting apart.
+//
+//go:embed schemamod
+var schemaModuleFS embed.FS
+
+// TaskSchema, SecuritySchema, OverlaySchema, CacheSchema, HookSchema and
+// EnvSchema describe the task and env shapes extractCueData decodes.
+// cue_emit_schema reflects over them to emit the matching
+// #Task/#Security/#Overlay/#Cache/#Hook/#Env CUE definitions, so the schema
+// user CUE files import is generated rather than hand-maintained.
+// extractCueData's own field lookups are written separately and are not
+// checked against these structs. They also accept two shapes the schema
+// does not: the boolean `cache: true`/`cache: false` shorthand (#Cache
+// covers only the structured block), and variables written directly as
+// keys of an `env` block (#Env declares a single `variables` map).
+type TaskSchema struct {
+	Description  string          `json:"description,omitempty" cue:"optional"`
+	Command      string          `json:"command,omitempty" cue:"optional"`
+	Script       string          `json:"script,omitempty" cue:"optional"`
+	Dependencies []string        `json:"dependencies,omitempty" cue:"optional"`
+	WorkingDir   string          `json:"workingDir,omitempty" cue:"optional"`
+	Shell        string          `json:"shell,omitempty" cue:"optional"`
+	Inputs       []string        `json:"inputs,omitempty" cue:"optional"`
+	Outputs      []string        `json:"outputs,omitempty" cue:"optional"`
+	Security     *SecuritySchema `json:"security,omitempty" cue:"optional"`
+	Cache        *CacheSchema    `json:"cache,omitempty" cue:"optional"`
+	CacheKey     string          `json:"cacheKey,omitempty" cue:"optional"`
+}
+
+type SecuritySchema struct {
+	RestrictDisk    bool            `json:"restrictDisk,omitempty" cue:"optional"`
+	RestrictNetwork bool            `json:"restrictNetwork,omitempty" cue:"optional"`
+	ReadOnlyPaths   []string        `json:"readOnlyPaths,omitempty" cue:"optional"`
+	ReadWritePaths  []string        `json:"readWritePaths,omitempty" cue:"optional"`
+	AllowedHosts    []string        `json:"allowedHosts,omitempty" cue:"optional"`
+	AllowNew        bool            `json:"allowNew,omitempty" cue:"optional"`
+	Overlays        []OverlaySchema `json:"overlays,omitempty" cue:"optional"`
+}
+
+type OverlaySchema struct {
+	LowerDir   string `json:"lowerDir" cue:"required"`
+	UpperDir   string `json:"upperDir,omitempty" cue:"optional"`
+	WorkDir    string `json:"workDir,omitempty" cue:"optional"`
+	MountPoint string `json:"mountPoint" cue:"required"`
+	ReadOnly   bool   `json:"readOnly,omitempty" cue:"optional"`
+}
+
+type CacheSchema struct {
+	Enabled     bool     `json:"enabled,omitempty" cue:"optional"`
+	Key         string   `json:"key,omitempty" cue:"optional"`
+	Inputs      []string `json:"inputs,omitempty" cue:"optional"`
+	Outputs     []string `json:"outputs,omitempty" cue:"optional"`
+	TTL         string   `json:"ttl,omitempty" cue:"optional"`
+	Scope       string   `json:"scope,omitempty" cue:"optional,enum=local|shared"`
+	Compression string   `json:"compression,omitempty" cue:"optional,enum=none|zstd|gzip"`
+}
+
+type HookSchema struct {
+	Command string   `json:"command" cue:"required"`
+	Args    []string `json:"args,omitempty" cue:"optional"`
+}
+
+type EnvSchema struct {
+	Variables map[string]interface{} `json:"variables,omitempty" cue:"optional"`
+}
+
+// cueSchemaDefs lists the structs cue_emit_schema reflects over, in the
+// order their #Definitions are emitted.
+var cueSchemaDefs = []struct {
+	name string
+	typ  reflect.Type
+}{
+	{"#Task", reflect.TypeOf(TaskSchema{})},
+	{"#Security", reflect.TypeOf(SecuritySchema{})},
+	{"#Overlay", reflect.TypeOf(OverlaySchema{})},
+	{"#Cache", reflect.TypeOf(CacheSchema{})},
+	{"#Hook", reflect.TypeOf(HookSchema{})},
+	{"#Env", reflect.TypeOf(EnvSchema{})},
+}
+
+// cueDefNameFor maps a schema struct type to its emitted CUE definition name,
+// so fields of that type (or slices/pointers to it) are reflected as a
+// reference to the definition rather than being inlined in place.
+var cueDefNameFor = func() map[reflect.Type]string {
+	names := make(map[reflect.Type]string, len(cueSchemaDefs))
+	for _, d := range cueSchemaDefs {
+		names[d.typ] = d.name
+	}
+	return names
+}()
+
+//export cue_emit_schema
+func cue_emit_schema() *C.char {
+	var result *C.char
+	defer func() {
+		if r := recover(); r != nil {
+			errMsg := map[string]string{"error": fmt.Sprintf("Internal error: %v", r)}
+			errBytes, _ := json.Marshal(errMsg)
+			result = C.CString(string(errBytes))
+		}
+	}()
+
+	result = C.CString(emitCueSchema())
+	return result
+}
+
+// cue_write_schema_module materializes the embedded cuenv.dev/schema CUE
+// module (schemaModuleFS) on disk under destDir, so user CUE files can add
+// destDir's parent to their module cache and write
+// `import "cuenv.dev/schema"` instead of hand-copying cue_emit_schema's
+// output. Safe to call repeatedly - existing files are overwritten.
+//
+//export cue_write_schema_module
+func cue_write_schema_module(destDir *C.char) *C.char {
+	var result *C.char
+	defer func() {
+		if r := recover(); r != nil {
+			errMsg := map[string]string{"error": fmt.Sprintf("Internal error: %v", r)}
+			errBytes, _ := json.Marshal(errMsg)
+			result = C.CString(string(errBytes))
+		}
+	}()
+
+	goDestDir := C.GoString(destDir)
+	if goDestDir == "" {
+		errMsg := map[string]string{"error": "Destination directory cannot be empty"}
+		errBytes, _ := json.Marshal(errMsg)
+		result = C.CString(string(errBytes))
+		return result
+	}
+
+	if err := extractSchemaModule(goDestDir); err != nil {
+		errMsg := map[string]string{"error": err.Error()}
+		errBytes, _ := json.Marshal(errMsg)
+		result = C.CString(string(errBytes))
+		return result
+	}
+
+	okBytes, _ := json.Marshal(map[string]string{"path": goDestDir})
+	result = C.CString(string(okBytes))
+	return result
+}
+
+// extractSchemaModule walks the embedded schemamod tree and writes every
+// file to the same relative path under destDir.
+func extractSchemaModule(destDir string) error {
+	return fs.WalkDir(schemaModuleFS, "schemamod", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel("schemamod", path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destDir, rel)
+
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		content, err := schemaModuleFS.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, 0644)
+	})
+}
+
+// emitCueSchema renders the CUE source for cuenv's schema on demand, the
+// same content checked into schemamod/schema.cue as the importable
+// cuenv.dev/schema module: borrowing the `cue get go` approach, it walks the
+// canonical Go structs and emits a matching #Definition for each, complete
+// with required/optional markers and enum constraints, so unknown fields in
+// user CUE files surface as constraint violations instead of being silently
+// dropped by the extractor.
+func emitCueSchema() string {
+	var b strings.Builder
+	b.WriteString("package schema\n\n")
+	for _, d := range cueSchemaDefs {
+		b.WriteString(d.name)
+		b.WriteString(": {\n")
+		writeCueFields(&b, d.typ, "\t")
+		b.WriteString("}\n\n")
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+func writeCueFields(b *strings.Builder, t reflect.Type, indent string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		jsonName := strings.Split(f.Tag.Get("json"), ",")[0]
+		if jsonName == "" {
+			jsonName = f.Name
+		}
+
+		cueTag := f.Tag.Get("cue")
+		label := jsonName
+		if strings.Contains(cueTag, "optional") {
+			label += "?"
+		}
+
+		b.WriteString(indent)
+		b.WriteString(label)
+		b.WriteString(": ")
+		b.WriteString(cueTypeFor(f.Type, cueTag))
+		b.WriteString("\n")
+	}
+}
+
+// cueTypeFor renders the CUE type for a reflected Go field, following
+// pointers and slices to referenced #Definitions and turning a `cue:"enum=a|b"`
+// tag into a disjunction of string literals.
+func cueTypeFor(t reflect.Type, cueTag string) string {
+	if idx := strings.Index(cueTag, "enum="); idx != -1 {
+		opts := strings.Split(cueTag[idx+len("enum="):], "|")
+		quoted := make([]string, len(opts))
+		for i, o := range opts {
+			quoted[i] = fmt.Sprintf("%q", o)
+		}
+		return strings.Join(quoted, " | ")
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Ptr:
+		if name, ok := cueDefNameFor[t.Elem()]; ok {
+			return name
+		}
+		return cueTypeFor(t.Elem(), "")
+	case reflect.Slice:
+		if name, ok := cueDefNameFor[t.Elem()]; ok {
+			return "[..." + name + "]"
+		}
+		return "[..." + cueTypeFor(t.Elem(), "") + "]"
+	case reflect.Map:
+		return "{[string]: _}"
+	default:
+		return "_"
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// TestEmbeddedSchemaMatchesGenerated guards against schemamod/schema.cue
+// (the checked-in, importable cuenv.dev/schema module) drifting from what
+// emitCueSchema reflects out of the TaskSchema/SecuritySchema/... structs -
+// the two are supposed to carry the same definitions by construction.
+func TestEmbeddedSchemaMatchesGenerated(t *testing.T) {
+	want := emitCueSchema()
+
+	got, err := schemaModuleFS.ReadFile("schemamod/schema.cue")
+	if err != nil {
+		t.Fatalf("Failed to read embedded schema.cue: %v", err)
+	}
+
+	// The committed file carries a generated-file header comment that
+	// emitCueSchema's output doesn't; compare from the package clause on.
+	gotStr := string(got)
+	if idx := strings.Index(gotStr, "package schema\n"); idx != -1 {
+		gotStr = gotStr[idx:]
+	}
+
+	if gotStr != want {
+		t.Errorf("schemamod/schema.cue is out of date with emitCueSchema; regenerate it.\nGenerated:\n%s\nEmbedded:\n%s", want, gotStr)
+	}
+}
+
+func TestCueEmitSchema_ContainsAllDefinitions(t *testing.T) {
+	out := emitCueSchema()
+
+	for _, name := range []string{"#Task", "#Security", "#Overlay", "#Cache", "#Hook", "#Env"} {
+		if !strings.Contains(out, name+": {") {
+			t.Errorf("Expected emitCueSchema output to define %s, got:\n%s", name, out)
+		}
+	}
+}
+
+func TestCueWriteSchemaModule_ExtractsFiles(t *testing.T) {
+	destDir, err := os.MkdirTemp("", "cuenv-schema-module-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(destDir)
+
+	if err := extractSchemaModule(destDir); err != nil {
+		t.Fatalf("extractSchemaModule failed: %v", err)
+	}
+
+	moduleFile := filepath.Join(destDir, "cue.mod", "module.cue")
+	if _, err := os.Stat(moduleFile); err != nil {
+		t.Errorf("Expected %s to exist: %v", moduleFile, err)
+	}
+
+	schemaFile := filepath.Join(destDir, "schema.cue")
+	content, err := os.ReadFile(schemaFile)
+	if err != nil {
+		t.Fatalf("Expected %s to exist: %v", schemaFile, err)
+	}
+	if !strings.Contains(string(content), "#Task: {") {
+		t.Errorf("Expected extracted schema.cue to define #Task, got:\n%s", content)
+	}
+}
+
+func TestCueWriteSchemaModule_EmptyDestDir(t *testing.T) {
+	cDestDir := C.CString("")
+	defer C.free(unsafe.Pointer(cDestDir))
+
+	result := cue_write_schema_module(cDestDir)
+	defer cue_free_string(result)
+
+	got := C.GoString(result)
+	if !strings.Contains(got, "error") {
+		t.Errorf("Expected an error for an empty destination directory, got: %s", got)
+	}
+}
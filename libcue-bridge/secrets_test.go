@@ -0,0 +1,166 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"cuelang.org/go/cue/cuecontext"
+)
+
+func TestExtractSecretReference_NoResolverIsNotASecret(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`value: "plain"`)
+	if v.Err() != nil {
+		t.Fatalf("Failed to compile CUE source: %v", v.Err())
+	}
+
+	ref, err := extractSecretReference(v)
+	if err != nil {
+		t.Fatalf("extractSecretReference failed: %v", err)
+	}
+	if ref != "" {
+		t.Errorf("Expected no reference for a value with no resolver, got %q", ref)
+	}
+}
+
+func TestExtractSecretReference_LegacyExecResolver(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`resolver: {cmd: "op", args: ["read", "item"]}`)
+	if v.Err() != nil {
+		t.Fatalf("Failed to compile CUE source: %v", v.Err())
+	}
+
+	ref, err := extractSecretReference(v)
+	if err != nil {
+		t.Fatalf("extractSecretReference failed: %v", err)
+	}
+	if !strings.HasPrefix(ref, "cuenv-resolver://") || strings.HasPrefix(ref, "cuenv-resolver://v2/") {
+		t.Errorf("Expected the unversioned legacy encoding, got %q", ref)
+	}
+	if !strings.Contains(ref, `"cmd":"op"`) {
+		t.Errorf("Expected the cmd field to be encoded, got %q", ref)
+	}
+}
+
+func TestExtractSecretReference_LegacyExecResolverAcceptsCommandAlias(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`resolver: {command: "op", args: ["read", "item"]}`)
+	if v.Err() != nil {
+		t.Fatalf("Failed to compile CUE source: %v", v.Err())
+	}
+
+	ref, err := extractSecretReference(v)
+	if err != nil {
+		t.Fatalf("extractSecretReference failed: %v", err)
+	}
+	if !strings.Contains(ref, `"cmd":"op"`) {
+		t.Errorf("Expected the command alias to be accepted as cmd, got %q", ref)
+	}
+}
+
+func TestExtractSecretReference_LegacyExecResolverRequiresArgs(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`resolver: {cmd: "op"}`)
+	if v.Err() != nil {
+		t.Fatalf("Failed to compile CUE source: %v", v.Err())
+	}
+
+	if _, err := extractSecretReference(v); err == nil {
+		t.Error("Expected an error when args is missing")
+	}
+}
+
+func TestExtractSecretReference_TypedKinds(t *testing.T) {
+	tests := []struct {
+		name   string
+		cueSrc string
+		kind   string
+		substr string
+	}{
+		{
+			name:   "vault",
+			cueSrc: `resolver: {kind: "vault", path: "secret/data/app", field: "password", mount: "secret"}`,
+			kind:   "vault",
+			substr: `"path":"secret/data/app"`,
+		},
+		{
+			name:   "aws-sm",
+			cueSrc: `resolver: {kind: "aws-sm", secretId: "prod/db", version: "AWSCURRENT", region: "us-east-1"}`,
+			kind:   "aws-sm",
+			substr: `"secretId":"prod/db"`,
+		},
+		{
+			name:   "gcp-sm",
+			cueSrc: `resolver: {kind: "gcp-sm", project: "my-proj", secret: "db-password", version: "latest"}`,
+			kind:   "gcp-sm",
+			substr: `"secret":"db-password"`,
+		},
+		{
+			name:   "op",
+			cueSrc: `resolver: {kind: "op", vault: "Engineering", item: "db-creds"}`,
+			kind:   "op",
+			substr: `"item":"db-creds"`,
+		},
+		{
+			name:   "file",
+			cueSrc: `resolver: {kind: "file", path: "/run/secrets/db", key: "password"}`,
+			kind:   "file",
+			substr: `"path":"/run/secrets/db"`,
+		},
+		{
+			name:   "exec",
+			cueSrc: `resolver: {kind: "exec", cmd: "op", args: ["read", "item"]}`,
+			kind:   "exec",
+			substr: `"cmd":"op"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := cuecontext.New()
+			v := ctx.CompileString(tt.cueSrc)
+			if v.Err() != nil {
+				t.Fatalf("Failed to compile CUE source: %v", v.Err())
+			}
+
+			ref, err := extractSecretReference(v)
+			if err != nil {
+				t.Fatalf("extractSecretReference failed: %v", err)
+			}
+
+			wantPrefix := "cuenv-resolver://v2/" + tt.kind + "/"
+			if !strings.HasPrefix(ref, wantPrefix) {
+				t.Errorf("Expected reference to start with %q, got %q", wantPrefix, ref)
+			}
+			if !strings.Contains(ref, tt.substr) {
+				t.Errorf("Expected reference to contain %q, got %q", tt.substr, ref)
+			}
+		})
+	}
+}
+
+func TestExtractSecretReference_UnknownKindErrors(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`resolver: {kind: "unknown-thing", foo: "bar"}`)
+	if v.Err() != nil {
+		t.Fatalf("Failed to compile CUE source: %v", v.Err())
+	}
+
+	_, err := extractSecretReference(v)
+	if err == nil || !strings.Contains(err.Error(), "unknown resolver kind") {
+		t.Errorf("Expected an unknown resolver kind error, got %v", err)
+	}
+}
+
+func TestExtractSecretReference_TypedKindMissingRequiredFieldErrors(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`resolver: {kind: "vault", path: "secret/data/app"}`)
+	if v.Err() != nil {
+		t.Fatalf("Failed to compile CUE source: %v", v.Err())
+	}
+
+	_, err := extractSecretReference(v)
+	if err == nil || !strings.Contains(err.Error(), `field`) {
+		t.Errorf("Expected a missing-field error naming the field, got %v", err)
+	}
+}